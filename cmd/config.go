@@ -0,0 +1,271 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/spf13/cobra"
+)
+
+var configShowResolved bool
+var configMigrateDryRun bool
+var configValidateStrict bool
+var configShareOutput string
+
+// configCmd groups commands that inspect and maintain pancake.yml.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and maintain pancake.yml.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print pancake.yml, or with --resolved the result of merging its includes.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return showConfig(configShowResolved) })
+		},
+	}
+	showCmd.Flags().BoolVar(&configShowResolved, "resolved", false, "Merge the files listed under 'include:' and show where each value comes from")
+
+	pullCmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Fetch the latest version of the git-hosted files listed under 'include:'.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(pullConfigIncludes)
+		},
+	}
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade pancake.yml to the current format version, keeping a backup.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return migrateConfig(configMigrateDryRun) })
+		},
+	}
+	migrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Show the changes as a diff without writing them")
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check pancake.yml and its includes, reporting every issue with its file, line and column.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return validateConfig(configValidateStrict) })
+		},
+	}
+	validateCmd.Flags().BoolVar(&configValidateStrict, "strict", false, "Fail on warnings as well as errors")
+
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print a JSON Schema for pancake.yml, for completion and checks in editors.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(printConfigSchema)
+		},
+	}
+
+	pathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print which config file pancake uses and why.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(printConfigPath)
+		},
+	}
+
+	shareCmd := &cobra.Command{
+		Use:   "share",
+		Short: "Print pancake.yml with API keys and other secrets removed, ready to share.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return shareConfig(configShareOutput) })
+		},
+	}
+	shareCmd.Flags().StringVarP(&configShareOutput, "output", "o", "", "Write to this file instead of stdout")
+
+	configCmd.AddCommand(showCmd, pullCmd, migrateCmd, validateCmd, schemaCmd, pathCmd, shareCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// runConfigCommand runs a config subcommand and exits with status 1 if it fails.
+func runConfigCommand(command func() error) {
+	if err := command(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// readConfigFile returns the path and raw contents of pancake.yml.
+func readConfigFile() (string, []byte, error) {
+	configPath, err := utils.ConfigPath()
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf(utils.ConfigErrNotFound, configPath)
+		}
+		return "", nil, fmt.Errorf(utils.ConfigErrReadFailed, configPath, configPath)
+	}
+	return configPath, data, nil
+}
+
+func showConfig(resolved bool) error {
+	configPath, data, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	if !resolved {
+		fmt.Print(string(data))
+		return nil
+	}
+	merged, err := utils.ResolveConfigIncludes(configPath, data, false)
+	if err != nil {
+		return fmt.Errorf(utils.ConfigErrIncludeFailed, err)
+	}
+	out, err := merged.Encode(true)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+func pullConfigIncludes() error {
+	configPath, data, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	if _, err := utils.ResolveConfigIncludes(configPath, data, true); err != nil {
+		return fmt.Errorf(utils.ConfigErrIncludeFailed, err)
+	}
+	fmt.Println("Includes are up to date.")
+	return nil
+}
+
+func migrateConfig(dryRun bool) error {
+	configPath, err := utils.ConfigPath()
+	if err != nil {
+		return err
+	}
+	migration, err := utils.MigrateConfig(configPath, dryRun)
+	if err != nil {
+		return err
+	}
+	if len(migration.Applied) == 0 {
+		fmt.Printf("pancake.yml is already at version %d.\n", utils.CurrentConfigVersion)
+		return nil
+	}
+	fmt.Printf("Migrations from version %d to %d:\n", migration.FromVersion, utils.CurrentConfigVersion)
+	for _, step := range migration.Applied {
+		fmt.Printf("  %s\n", step)
+	}
+	if dryRun {
+		fmt.Println()
+		fmt.Print(utils.UnifiedDiff(string(migration.Original), string(migration.Migrated), configPath, configPath+" (migrated)"))
+		fmt.Println("\nDry run: pancake.yml was not changed.")
+		return nil
+	}
+	fmt.Printf("Upgraded pancake.yml to version %d.\n", utils.CurrentConfigVersion)
+	fmt.Printf("The previous file is saved as %s.\n", migration.Backup)
+	return nil
+}
+
+func validateConfig(strict bool) error {
+	configPath, err := utils.ConfigPath()
+	if err != nil {
+		return err
+	}
+	diagnostics, err := utils.ValidateConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	if len(diagnostics) == 0 {
+		fmt.Println("pancake.yml is valid.")
+		return nil
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, diagnostic := range diagnostics {
+		severity := "error"
+		if diagnostic.Warning {
+			severity = "warning"
+			warningCount++
+		} else {
+			errorCount++
+		}
+		lines := strings.Split(diagnostic.Message, "\n")
+		fmt.Printf("%s: %s: %s\n", diagnostic.Position(), severity, lines[0])
+		for _, line := range lines[1:] {
+			if line != "Run 'pancake edit config'." {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	fmt.Printf("\n%d error(s), %d warning(s).\n", errorCount, warningCount)
+	if errorCount > 0 || strict && warningCount > 0 {
+		return fmt.Errorf("pancake.yml has issues; run 'pancake edit config' to fix them")
+	}
+	return nil
+}
+
+func printConfigSchema() error {
+	schema, err := utils.ConfigJSONSchema()
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(schema))
+	return nil
+}
+
+func printConfigPath() error {
+	configPath, source, err := utils.FindConfigPath()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s)\n", configPath, source)
+	if !utils.CheckExists(configPath) {
+		fmt.Println("It does not exist yet; run 'pancake init' to create it.")
+	}
+	return nil
+}
+
+func shareConfig(output string) error {
+	_, data, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	shared, stripped, err := utils.StripSecrets(data)
+	if err != nil {
+		return fmt.Errorf("could not parse pancake.yml: %w", err)
+	}
+	if output == "" {
+		fmt.Print(string(shared))
+	} else if err := os.WriteFile(output, shared, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", output, err)
+	}
+	for _, path := range stripped {
+		fmt.Fprintf(os.Stderr, "Removed secret: %s\n", path)
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %s.\n", output)
+	}
+	return nil
+}
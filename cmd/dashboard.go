@@ -0,0 +1,186 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/eiannone/keyboard"
+)
+
+/*
+- This will have implementation of "pancake monitor --watch".
+It redraws the monitor table on an interval and lets the user pick a project
+with the arrow keys and start, stop, restart or tail its logs in place.
+*/
+
+const (
+	clearScreen      = "\x1b[H\x1b[2J"
+	dashboardLogTail = 30
+)
+
+var (
+	dashboardTitleStyle     = lipgloss.NewStyle().Bold(true)
+	dashboardHeaderStyle    = lipgloss.NewStyle().Bold(true)
+	dashboardSelectedStyle  = lipgloss.NewStyle().Reverse(true)
+	dashboardRunningStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	dashboardUnhealthyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	dashboardHelpStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+// watchProjects runs the live dashboard until the user quits.
+func watchProjects(interval time.Duration) {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		fmt.Printf("Error: could not read keyboard input: %v\n", err)
+		fmt.Println("Run 'pancake monitor' without --watch for a one-off table.")
+		return
+	}
+	defer keyboard.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	selected := 0
+	message := ""
+	for {
+		rows, pruned := collectProjectStatus()
+		if len(pruned) > 0 {
+			message = "Removed stale entries from pids.json: " + strings.Join(pruned, ", ")
+		}
+		if selected >= len(rows) {
+			selected = len(rows) - 1
+		}
+		if selected < 0 {
+			selected = 0
+		}
+		renderDashboard(rows, selected, interval, message)
+
+		select {
+		case <-ticker.C:
+			continue
+		case event := <-keys:
+			if event.Err != nil {
+				fmt.Printf("Error reading keypress: %v\n", event.Err)
+				return
+			}
+			switch {
+			case event.Key == keyboard.KeyCtrlC || event.Key == keyboard.KeyEsc || event.Rune == 'q':
+				fmt.Print(clearScreen)
+				return
+			case event.Key == keyboard.KeyArrowUp || event.Rune == 'k':
+				selected--
+			case event.Key == keyboard.KeyArrowDown || event.Rune == 'j':
+				selected++
+			case len(rows) == 0:
+				continue
+			case event.Rune == 's':
+				message = dashboardAction(keys, rows[selected][0], runSingleProject)
+			case event.Rune == 'x':
+				message = dashboardAction(keys, rows[selected][0], stopSingleProject)
+			case event.Rune == 'r':
+				message = dashboardAction(keys, rows[selected][0], restartSingleProject)
+			case event.Rune == 'l':
+				message = dashboardAction(keys, rows[selected][0], showProjectLogTail)
+			}
+		}
+	}
+}
+
+func renderDashboard(rows [][]string, selected int, interval time.Duration, message string) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	b.WriteString(dashboardTitleStyle.Render("Pancake Monitor"))
+	b.WriteString(fmt.Sprintf("  %s (every %s)\n\n", time.Now().Format("15:04:05"), interval))
+
+	widths := make([]int, len(monitorHeader))
+	for _, row := range append([][]string{monitorHeader}, rows...) {
+		for i, col := range row {
+			if len(col) > widths[i] {
+				widths[i] = len(col)
+			}
+		}
+	}
+	b.WriteString("  " + dashboardHeaderStyle.Render(formatDashboardRow(monitorHeader, widths)) + "\n")
+	if len(rows) == 0 {
+		b.WriteString("  No projects in pancake.yml. Run 'pancake edit config' to add one.\n")
+	}
+	for i, row := range rows {
+		line := formatDashboardRow(row, widths)
+		switch {
+		case i == selected:
+			b.WriteString("> " + dashboardSelectedStyle.Render(line))
+		case row[1] == utils.ProcessRunning && row[8] == utils.HealthUnhealthy:
+			b.WriteString("  " + dashboardUnhealthyStyle.Render(line))
+		case row[1] == utils.ProcessRunning:
+			b.WriteString("  " + dashboardRunningStyle.Render(line))
+		default:
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if message != "" {
+		b.WriteString(message + "\n")
+	}
+	b.WriteString(dashboardHelpStyle.Render("↑/↓ select · s start · x stop · r restart · l logs · q quit"))
+	b.WriteString("\n")
+	fmt.Print(b.String())
+}
+
+func formatDashboardRow(row []string, widths []int) string {
+	cols := make([]string, len(row))
+	for i, col := range row {
+		cols[i] = fmt.Sprintf("%-*s", widths[i], col)
+	}
+	return strings.Join(cols, "  ")
+}
+
+// dashboardAction clears the dashboard, runs action for the selected project
+// with its normal output, and waits for a key before going back.
+func dashboardAction(keys <-chan keyboard.KeyEvent, projectName string, action func(string)) string {
+	fmt.Print(clearScreen)
+	action(projectName)
+	fmt.Print("\nPress any key to return to the dashboard...")
+	<-keys
+	return fmt.Sprintf("Last action on %s finished at %s.", projectName, time.Now().Format("15:04:05"))
+}
+
+// showProjectLogTail prints the most recent lines of a project's log.
+func showProjectLogTail(projectName string) {
+	logPath := utils.ProjectLogPath(config.Home, projectName)
+	entries, err := utils.ReadLogEntries(logPath, projectName)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", logPath, err)
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No logs for project %s yet. Start it with 's' first.\n", projectName)
+		return
+	}
+	if len(entries) > dashboardLogTail {
+		entries = entries[len(entries)-dashboardLogTail:]
+	}
+	fmt.Printf("Last %d lines of %s (run 'pancake logs %s -f' to follow):\n\n", len(entries), logPath, projectName)
+	for _, entry := range entries {
+		fmt.Println(formatLogEntry(entry))
+	}
+}
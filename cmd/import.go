@@ -0,0 +1,219 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/spf13/cobra"
+)
+
+var importYes bool
+
+var importCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Find git repositories under a directory and add them to pancake.yml.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !loadConfig() {
+			os.Exit(1)
+		}
+		if err := importProjects(args[0]); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	importCmd.Flags().BoolVarP(&importYes, "yes", "y", false, "Add every repository found without asking")
+
+	projectCmd.AddCommand(importCmd)
+}
+
+// importProjects adds the repositories found under dir to pancake.yml after
+// the user has reviewed each one.
+func importProjects(dir string) error {
+	root, err := utils.ExpandHomePath(dir)
+	if err != nil {
+		return err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return err
+	}
+
+	fmt.Printf("Looking for git repositories in %s...\n", root)
+	repos, skipped, err := utils.DiscoverRepositories(root)
+	if err != nil {
+		return fmt.Errorf("could not search %s: %w", root, err)
+	}
+	for _, path := range skipped {
+		fmt.Printf("Skipping %s: it has no 'origin' remote.\n", path)
+	}
+
+	knownRemotes := make(map[string]string)
+	for projectName, project := range config.Projects {
+		knownRemotes[project.RemoteSSHURL] = projectName
+	}
+	var candidates []utils.DiscoveredRepository
+	for _, repo := range repos {
+		if projectName, ok := knownRemotes[repo.Project.RemoteSSHURL]; ok {
+			fmt.Printf("Skipping %s: already in pancake.yml as '%s'.\n", repo.Path, projectName)
+			continue
+		}
+		candidates = append(candidates, repo)
+	}
+	if len(candidates) == 0 {
+		fmt.Println("No new repositories found.")
+		return nil
+	}
+	fmt.Printf("Found %d new repositories.\n", len(candidates))
+
+	if config.Projects == nil {
+		config.Projects = make(map[string]utils.Project)
+	}
+	imported, err := reviewImports(candidates, bufio.NewReader(os.Stdin))
+	if err != nil {
+		return err
+	}
+	if len(imported) == 0 {
+		fmt.Println("No projects imported.")
+		return nil
+	}
+
+	err = utils.ModifyConfig(func(cfg *utils.Config) error {
+		for _, repo := range imported {
+			if err := utils.AddProject(cfg, repo.Name, repo.Project); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d projects into pancake.yml.\n", len(imported))
+	for _, repo := range imported {
+		if projectPath := filepath.Join(config.Home, repo.Name); filepath.Clean(repo.Path) != projectPath {
+			fmt.Printf("'%s' is checked out at %s, but pancake works in %s. Move the checkout there or run 'pancake sync %s' to clone it.\n", repo.Name, repo.Path, projectPath, repo.Name)
+		}
+	}
+	return nil
+}
+
+// reviewImports asks about each candidate in turn and adds the accepted ones
+// to config.Projects. It returns the repositories that were added, under the
+// project name they were added as.
+func reviewImports(candidates []utils.DiscoveredRepository, reader *bufio.Reader) ([]utils.DiscoveredRepository, error) {
+	var imported []utils.DiscoveredRepository
+	acceptAll := importYes
+	for _, repo := range candidates {
+		if _, taken := config.Projects[repo.Name]; taken {
+			repo.Name = filepath.Base(filepath.Dir(repo.Path)) + "-" + repo.Name
+		}
+		if !acceptAll {
+			printImportCandidate(repo)
+		}
+		for !acceptAll {
+			answer, err := prompt(reader, "Add this project? [y]es / [n]o / [e]dit / [a]ll / [q]uit: ")
+			if err != nil {
+				return nil, err
+			}
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+			case "a", "all":
+				acceptAll = true
+			case "n", "no":
+				repo.Name = ""
+			case "e", "edit":
+				if repo, err = editImportCandidate(repo, reader); err != nil {
+					return nil, err
+				}
+				printImportCandidate(repo)
+				continue
+			case "q", "quit":
+				return imported, nil
+			default:
+				continue
+			}
+			break
+		}
+		if repo.Name == "" {
+			continue
+		}
+		if _, taken := config.Projects[repo.Name]; taken {
+			fmt.Printf("Skipping %s: a project named '%s' already exists. Use [e]dit to choose another name.\n", repo.Path, repo.Name)
+			continue
+		}
+		config.Projects[repo.Name] = repo.Project
+		imported = append(imported, repo)
+	}
+	return imported, nil
+}
+
+func printImportCandidate(repo utils.DiscoveredRepository) {
+	fmt.Println()
+	fmt.Printf("%s (%s)\n", repo.Name, repo.Path)
+	fmt.Printf("   remote: %s\n", repo.Project.RemoteSSHURL)
+	for _, field := range [][2]string{{"type", repo.Project.Type}, {"build", repo.Project.Build}, {"run", repo.Project.Run}} {
+		if field[1] != "" {
+			fmt.Printf("   %s: %s\n", field[0], field[1])
+		}
+	}
+}
+
+// editImportCandidate lets the user change the guessed name and commands.
+// An empty answer keeps the current value and '-' clears it.
+func editImportCandidate(repo utils.DiscoveredRepository, reader *bufio.Reader) (utils.DiscoveredRepository, error) {
+	fields := []struct {
+		label string
+		value *string
+	}{
+		{"name", &repo.Name},
+		{"type", &repo.Project.Type},
+		{"build", &repo.Project.Build},
+		{"run", &repo.Project.Run},
+	}
+	for _, field := range fields {
+		answer, err := prompt(reader, fmt.Sprintf("   %s [%s]: ", field.label, *field.value))
+		if err != nil {
+			return repo, err
+		}
+		switch answer {
+		case "":
+		case "-":
+			*field.value = ""
+		default:
+			*field.value = answer
+		}
+	}
+	return repo, nil
+}
+
+func prompt(reader *bufio.Reader, message string) (string, error) {
+	fmt.Print(message)
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", errors.New("no answer given; run with --yes to import without asking")
+	}
+	return strings.TrimSpace(answer), nil
+}
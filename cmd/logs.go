@@ -0,0 +1,186 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsSince  string
+	logsGrep   string
+	logsLines  int
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [project_name...]",
+	Short: "Show the captured output of projects started with 'pancake run'.",
+	Run: func(cmd *cobra.Command, args []string) {
+		showLogs(args)
+	},
+}
+
+// logPrefixColors cycles through ANSI colors so each project's lines stand out.
+var logPrefixColors = []string{"6", "3", "5", "2", "4", "1"}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new lines as they are written")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only show lines newer than a duration (10m, 2h) or a time (2006-01-02 15:04)")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of lines to show before following (0 shows everything)")
+
+	projectCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(logsCmd)
+}
+
+func showLogs(args []string) {
+	if !loadConfig() {
+		return
+	}
+	projectNames := args
+	if len(projectNames) == 0 {
+		for projectName := range config.Projects {
+			if len(utils.LogFiles(utils.ProjectLogPath(config.Home, projectName))) > 0 {
+				projectNames = append(projectNames, projectName)
+			}
+		}
+		sort.Strings(projectNames)
+		if len(projectNames) == 0 {
+			fmt.Println("No project logs yet. Run 'pancake run <project_name>' to start a project.")
+			return
+		}
+	} else {
+		for _, projectName := range projectNames {
+			if _, ok := getProject(projectName); !ok {
+				return
+			}
+		}
+	}
+
+	since, err := parseSince(logsSince, time.Now())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	var pattern *regexp.Regexp
+	if logsGrep != "" {
+		if pattern, err = regexp.Compile(logsGrep); err != nil {
+			fmt.Printf("Error: invalid --grep pattern: %v\n", err)
+			return
+		}
+	}
+	keep := func(entry utils.LogEntry) bool {
+		if !since.IsZero() && entry.Time.Before(since) {
+			return false
+		}
+		return pattern == nil || pattern.MatchString(entry.Text)
+	}
+
+	prefixes := make(map[string]string)
+	if len(projectNames) > 1 || len(args) == 0 {
+		width := 0
+		for _, projectName := range projectNames {
+			width = max(width, len(projectName))
+		}
+		for i, projectName := range projectNames {
+			style := lipgloss.NewStyle().Foreground(lipgloss.Color(logPrefixColors[i%len(logPrefixColors)]))
+			prefixes[projectName] = style.Render(fmt.Sprintf("%-*s |", width, projectName)) + " "
+		}
+	}
+
+	var groups [][]utils.LogEntry
+	for _, projectName := range projectNames {
+		entries, err := utils.ReadLogEntries(utils.ProjectLogPath(config.Home, projectName), projectName)
+		if err != nil {
+			fmt.Printf("Error reading logs of project %s: %v\n", projectName, err)
+			return
+		}
+		groups = append(groups, entries)
+	}
+	var shown []utils.LogEntry
+	for _, entry := range utils.MergeLogEntries(groups...) {
+		if keep(entry) {
+			shown = append(shown, entry)
+		}
+	}
+	if logsLines > 0 && len(shown) > logsLines {
+		shown = shown[len(shown)-logsLines:]
+	}
+	for _, entry := range shown {
+		fmt.Println(prefixes[entry.Project] + formatLogEntry(entry))
+	}
+
+	if logsFollow {
+		followLogs(projectNames, prefixes, keep)
+	}
+}
+
+// followLogs polls the projects' log files and prints new lines until interrupted.
+func followLogs(projectNames []string, prefixes map[string]string, keep func(utils.LogEntry) bool) {
+	followers := make([]*utils.LogFollower, len(projectNames))
+	for i, projectName := range projectNames {
+		followers[i] = utils.NewLogFollower(utils.ProjectLogPath(config.Home, projectName), projectName)
+	}
+	for {
+		time.Sleep(500 * time.Millisecond)
+		var groups [][]utils.LogEntry
+		for _, follower := range followers {
+			entries, err := follower.Poll()
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+			groups = append(groups, entries)
+		}
+		for _, entry := range utils.MergeLogEntries(groups...) {
+			if keep(entry) {
+				fmt.Println(prefixes[entry.Project] + formatLogEntry(entry))
+			}
+		}
+	}
+}
+
+func formatLogEntry(entry utils.LogEntry) string {
+	if entry.Time.IsZero() {
+		return strings.Repeat(" ", 19) + " " + entry.Text
+	}
+	return entry.Time.Local().Format("2006-01-02 15:04:05") + " " + entry.Text
+}
+
+// parseSince accepts a duration relative to now ("10m", "2h") or an absolute
+// time ("2006-01-02 15:04", "2006-01-02" or RFC 3339).
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value '%s'. Use a duration like 10m or 2h, or a time like '2006-01-02 15:04'", value)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/spf13/cobra"
+)
+
+var addProjectSettings utils.Project
+var addProjectName string
+var removeDeleteDir bool
+
+var addCmd = &cobra.Command{
+	Use:   "add <remote_url>",
+	Short: "Add a project to pancake.yml from its git remote URL.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runConfigEdit(func() error { return addProject(args[0]) })
+	},
+}
+
+var removeCmd = &cobra.Command{
+	Use:               "remove <project_name>",
+	Aliases:           []string{"rm"},
+	Short:             "Remove a project from pancake.yml, optionally deleting its directory.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProjectNames,
+	Run: func(cmd *cobra.Command, args []string) {
+		runConfigEdit(func() error { return removeProject(args[0]) })
+	},
+}
+
+var renameCmd = &cobra.Command{
+	Use:               "rename <project_name> <new_name>",
+	Aliases:           []string{"mv"},
+	Short:             "Rename a project and move its checkout to match.",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjectNames,
+	Run: func(cmd *cobra.Command, args []string) {
+		runConfigEdit(func() error { return renameProject(args[0], args[1]) })
+	},
+}
+
+func init() {
+	addCmd.Flags().StringVar(&addProjectName, "name", "", "Project name (defaults to the repository name from the URL)")
+	addCmd.Flags().StringVar(&addProjectSettings.Type, "type", "", "Project type, e.g. web")
+	addCmd.Flags().StringVar(&addProjectSettings.Port, "port", "", "Port the project listens on")
+	addCmd.Flags().StringVar(&addProjectSettings.Build, "build", "", "Command that builds the project")
+	addCmd.Flags().StringVar(&addProjectSettings.Run, "run", "", "Command that runs the project")
+	addCmd.Flags().StringVar(&addProjectSettings.Branch, "branch", "", "Branch to clone and pull")
+	removeCmd.Flags().BoolVar(&removeDeleteDir, "delete", false, "Also delete the project's directory after confirmation")
+
+	projectCmd.AddCommand(addCmd, removeCmd, renameCmd)
+}
+
+// runConfigEdit loads pancake.yml, runs edit and exits with status 1 if
+// either fails.
+func runConfigEdit(edit func() error) {
+	if !loadConfig() {
+		os.Exit(1)
+	}
+	if err := edit(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+func addProject(remoteURL string) error {
+	projectName := addProjectName
+	if projectName == "" {
+		projectName = utils.ProjectNameFromRemote(remoteURL)
+	}
+	project := addProjectSettings
+	project.RemoteSSHURL = remoteURL
+	err := utils.ModifyConfig(func(cfg *utils.Config) error {
+		return utils.AddProject(cfg, projectName, project)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added project %s.\n", projectName)
+	fmt.Printf("\nTip: Run 'pancake sync %s' to clone it.\n", projectName)
+	return nil
+}
+
+func removeProject(projectName string) error {
+	if err := ensureNotRunning(projectName); err != nil {
+		return err
+	}
+	projectPath, err := utils.ProjectDir(config.Home, projectName)
+	if err != nil {
+		return err
+	}
+	err = utils.ModifyConfig(func(cfg *utils.Config) error {
+		dependents, err := utils.RemoveProject(cfg, projectName)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			return fmt.Errorf("project '%s' is needed by %s; remove it from their 'depends_on' first", projectName, strings.Join(dependents, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed project %s from pancake.yml.\n", projectName)
+
+	if !utils.CheckExists(projectPath) {
+		return nil
+	}
+	if !removeDeleteDir {
+		fmt.Printf("Its checkout is still at %s.\n", projectPath)
+		return nil
+	}
+	message := fmt.Sprintf("Delete %s and everything in it? (yes/no) ", projectPath)
+	if dirty, err := utils.WorkingTreeDirty(projectPath); err == nil && dirty {
+		message = fmt.Sprintf("Warning: %s has uncommitted changes. ", projectPath) + message
+	}
+	if !utils.ConfirmAction(message) {
+		fmt.Printf("Kept %s.\n", projectPath)
+		return nil
+	}
+	if err := os.RemoveAll(projectPath); err != nil {
+		return fmt.Errorf("could not delete %s: %w", projectPath, err)
+	}
+	for _, logFile := range utils.LogFiles(utils.ProjectLogPath(config.Home, projectName)) {
+		os.Remove(logFile)
+	}
+	fmt.Printf("Deleted %s.\n", projectPath)
+	return nil
+}
+
+func renameProject(oldName, newName string) error {
+	if err := ensureNotRunning(oldName); err != nil {
+		return err
+	}
+	oldPath, err := utils.ProjectDir(config.Home, oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := utils.ProjectDir(config.Home, newName)
+	if err != nil {
+		return err
+	}
+	moved := false
+	err = utils.ModifyConfig(func(cfg *utils.Config) error {
+		if err := utils.RenameProject(cfg, oldName, newName); err != nil {
+			return err
+		}
+		if err := utils.ValidateConfig(cfg); err != nil {
+			return err
+		}
+		if !utils.CheckExists(oldPath) {
+			return nil
+		}
+		if utils.CheckExists(newPath) {
+			return fmt.Errorf("cannot move %s: %s already exists", oldPath, newPath)
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return fmt.Errorf("could not move %s to %s: %w", oldPath, newPath, err)
+		}
+		moved = true
+		return nil
+	})
+	if err != nil {
+		if moved {
+			os.Rename(newPath, oldPath)
+		}
+		return err
+	}
+
+	oldLog := utils.ProjectLogPath(config.Home, oldName)
+	newLog := utils.ProjectLogPath(config.Home, newName)
+	for _, logFile := range utils.LogFiles(oldLog) {
+		os.Rename(logFile, newLog+strings.TrimPrefix(logFile, oldLog))
+	}
+
+	fmt.Printf("Renamed project %s to %s.\n", oldName, newName)
+	if moved {
+		fmt.Printf("Moved its checkout to %s.\n", newPath)
+	}
+	return nil
+}
+
+// ensureNotRunning refuses to change a project that pancake has started, as
+// its PID is recorded under the project name and its directory is in use.
+func ensureNotRunning(projectName string) error {
+	if err := utils.LoadProjectProcesses(config.Home, &projectProcesses); err != nil {
+		return fmt.Errorf("could not load project PIDs: %w", err)
+	}
+	if record, exists := projectProcesses[projectName]; exists {
+		if status, _ := utils.CheckProcess(record); status == utils.ProcessRunning {
+			return fmt.Errorf("project '%s' is running (PID %d); run 'pancake stop %s' first", projectName, record.PID, projectName)
+		}
+	}
+	return nil
+}
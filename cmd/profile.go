@@ -0,0 +1,110 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/spf13/cobra"
+)
+
+// profileCmd shows and switches the profile pancake works with.
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Show the active profile, or list and switch profiles.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runConfigEdit(showProfile)
+	},
+}
+
+func init() {
+	useCmd := &cobra.Command{
+		Use:               "use <profile_name>",
+		Short:             "Make a profile from 'profiles:' the active one ('default' for the top-level settings).",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProfileNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return useProfile(args[0]) })
+		},
+	}
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the profiles in pancake.yml.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigEdit(listProfiles)
+		},
+	}
+	profileCmd.AddCommand(useCmd, listCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+func showProfile() error {
+	fmt.Printf("Active profile: %s\n", config.ActiveProfile())
+	fmt.Printf("  home:     %s\n", config.Home)
+	fmt.Printf("  projects: %d\n", len(config.Projects))
+	fmt.Printf("  tools:    %d\n", len(config.Tools))
+	if config.DefaultAI != "" {
+		fmt.Printf("  ai:       %s\n", config.DefaultAI)
+	}
+	return nil
+}
+
+func listProfiles() error {
+	for _, name := range utils.ProfileNames(&config) {
+		marker := " "
+		if name == config.ActiveProfile() {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+	return nil
+}
+
+// useProfile records name under 'profile:' in pancake.yml. It does not load
+// the configuration first, so it can also repair a selection of a profile
+// that no longer exists.
+func useProfile(name string) error {
+	err := utils.ModifyConfig(func(cfg *utils.Config) error {
+		if _, exists := cfg.Profiles[name]; !exists && name != utils.DefaultProfileName {
+			return fmt.Errorf("profile '%s' is not defined under 'profiles:' in pancake.yml", name)
+		}
+		cfg.Profile = name
+		if name == utils.DefaultProfileName {
+			cfg.Profile = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Switched to profile %s.\n", name)
+	return nil
+}
+
+func completeProfileNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := utils.GetConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return utils.ProfileNames(cfg), cobra.ShellCompDirectiveNoFileComp
+}
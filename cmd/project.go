@@ -16,8 +16,16 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/a6h15hek/pancake/utils"
 	"github.com/atotto/clipboard"
@@ -33,19 +41,61 @@ var projectCmd = &cobra.Command{
 	},
 }
 
-var projectPIDs = make(map[string]int)
+var projectProcesses = make(map[string]utils.ProcessRecord)
+var runInTerminal bool
+var runForce bool
+var stopGracePeriod time.Duration
+var monitorWatch bool
+var monitorInterval time.Duration
+var projectJobs int
+var syncAutoStash bool
+var selectGroups []string
+var selectTags []string
 
 func init() {
 	rootCmd.AddCommand(projectCmd)
 
+	runCmd := &cobra.Command{Use: "run", Aliases: []string{"r"}, Run: func(cmd *cobra.Command, args []string) { runProject(args) }}
+	runCmd.Flags().BoolVar(&runInTerminal, "terminal", false, "Open the project in a new terminal window instead of running it in the background")
+	runCmd.Flags().BoolVar(&runForce, "force", false, "Start the project even if its port is already in use")
+
+	stopCmd := &cobra.Command{Use: "stop", Run: func(cmd *cobra.Command, args []string) { stopProject(args) }}
+	stopCmd.Flags().DurationVar(&stopGracePeriod, "grace", 10*time.Second, "How long to wait after SIGTERM before force-killing the project")
+	restartCmd := &cobra.Command{Use: "restart", Run: func(cmd *cobra.Command, args []string) { restartProject(args) }}
+	restartCmd.Flags().DurationVar(&stopGracePeriod, "grace", 10*time.Second, "How long to wait after SIGTERM before force-killing the project")
+
+	monitorCmd := &cobra.Command{Use: "monitor", Aliases: []string{"m"}, Run: func(cmd *cobra.Command, args []string) { monitorProject() }}
+	monitorCmd.Flags().BoolVarP(&monitorWatch, "watch", "w", false, "Show a live dashboard that refreshes until you press q")
+	monitorCmd.Flags().DurationVar(&monitorInterval, "interval", 2*time.Second, "Refresh interval for --watch")
+
+	syncCmd := &cobra.Command{Use: "sync", Aliases: []string{"s"}, Run: func(cmd *cobra.Command, args []string) {
+		if err := syncProjects(args); err != nil {
+			os.Exit(1)
+		}
+	}}
+	syncCmd.Flags().IntVarP(&projectJobs, "jobs", "j", 4, "Number of projects to sync at the same time when syncing all projects")
+	syncCmd.Flags().BoolVar(&syncAutoStash, "autostash", false, "Stash uncommitted changes before pulling and restore them afterwards")
+	buildCmd := &cobra.Command{Use: "build", Aliases: []string{"b"}, Run: func(cmd *cobra.Command, args []string) { buildProject(args) }}
+	buildCmd.Flags().IntVarP(&projectJobs, "jobs", "j", 4, "Number of projects to build at the same time when building all projects")
+
+	doCmd := &cobra.Command{Use: "do", Run: func(cmd *cobra.Command, args []string) { doProjectTask(args) }, ValidArgsFunction: completeProjectTasks}
+
+	for _, cmd := range []*cobra.Command{syncCmd, buildCmd, runCmd, stopCmd, restartCmd} {
+		addSelectorFlags(cmd)
+	}
+
 	var commandList = []*cobra.Command{
 		{Use: "list", Aliases: []string{"l"}, Run: func(cmd *cobra.Command, args []string) { listProjects() }},
 		{Use: "pwd", Aliases: []string{"p"}, Run: func(cmd *cobra.Command, args []string) { pwdProject(args) }},
-		{Use: "sync", Aliases: []string{"s"}, Run: func(cmd *cobra.Command, args []string) { syncProjects(args) }},
+		{Use: "env", Run: func(cmd *cobra.Command, args []string) { envProject(args) }},
+		doCmd,
+		syncCmd,
 		{Use: "open", Aliases: []string{"o"}, Run: func(cmd *cobra.Command, args []string) { openProject(args) }},
-		{Use: "build", Aliases: []string{"b"}, Run: func(cmd *cobra.Command, args []string) { buildProject(args) }},
-		{Use: "run", Aliases: []string{"r"}, Run: func(cmd *cobra.Command, args []string) { runProject(args) }},
-		{Use: "monitor", Aliases: []string{"m"}, Run: func(cmd *cobra.Command, args []string) { monitorProject() }},
+		buildCmd,
+		runCmd,
+		stopCmd,
+		restartCmd,
+		monitorCmd,
 	}
 
 	projectCmd.AddCommand(commandList...)
@@ -65,18 +115,164 @@ func loadConfig() bool {
 }
 
 func handleProjectAction(args []string, action func(string)) {
-	if !loadConfig() {
+	projectNames, ok := selectProjectsInOrder(args)
+	if !ok {
 		return
 	}
-	if len(args) == 0 {
-		if utils.ConfirmAction("Are you sure you want to run for all projects? This may take some time. (yes/no)") {
-			for projectName := range config.Projects {
-				action(projectName)
+	for _, projectName := range projectNames {
+		action(projectName)
+	}
+}
+
+// selectProjectsInOrder loads pancake.yml and returns the selected projects
+// with each one after the projects it depends on, asking first when every
+// project is selected.
+func selectProjectsInOrder(args []string) ([]string, bool) {
+	if !loadConfig() {
+		return nil, false
+	}
+	projectNames, all, ok := selectProjects(args)
+	if !ok {
+		return nil, false
+	}
+	if all && !utils.ConfirmAction("Are you sure you want to run for all projects? This may take some time. (yes/no)") {
+		return nil, false
+	}
+	return utils.SortByDependencies(config.Projects, projectNames), true
+}
+
+// selectProjects resolves the projects an action applies to: the projects
+// named in args plus those matching --group and --tag, or every project
+// (all is true) when nothing was selected.
+func selectProjects(args []string) (projectNames []string, all bool, ok bool) {
+	if len(args) == 0 && len(selectGroups) == 0 && len(selectTags) == 0 {
+		for projectName := range config.Projects {
+			projectNames = append(projectNames, projectName)
+		}
+		sort.Strings(projectNames)
+		return projectNames, true, true
+	}
+	selected, err := utils.SelectProjects(&config, selectGroups, selectTags)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return nil, false, false
+	}
+	for _, projectName := range args {
+		if _, ok := getProject(projectName); !ok {
+			return nil, false, false
+		}
+		if !slices.Contains(selected, projectName) {
+			selected = append(selected, projectName)
+		}
+	}
+	sort.Strings(selected)
+	return selected, false, true
+}
+
+// addSelectorFlags lets a command act on groups and tagged projects.
+func addSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&selectGroups, "group", "g", nil, "Act on the projects of a group from 'groups:' in pancake.yml (repeatable)")
+	cmd.Flags().StringSliceVarP(&selectTags, "tag", "t", nil, "Act on the projects with this tag (repeatable)")
+}
+
+// handleParallelProjectAction runs action for a single selected project, or
+// for several projects at once on up to --jobs workers. In that case each
+// project's output is printed as one prefixed block when it finishes,
+// followed by a summary. With withDependencies, a project's depends_on are
+// handled before it. tip is printed after a successful single-project run.
+// The returned error says whether any project failed; details have already
+// been printed.
+func handleParallelProjectAction(args []string, action func(string, io.Writer) (string, error), withDependencies bool, tip string) error {
+	if !loadConfig() {
+		return errors.New("could not load pancake.yml")
+	}
+	projectNames, all, ok := selectProjects(args)
+	if !ok {
+		return errors.New("no projects selected")
+	}
+	if len(projectNames) == 0 {
+		fmt.Println("No projects selected.")
+		return nil
+	}
+	if len(projectNames) == 1 && !all {
+		projectName := projectNames[0]
+		order := []string{projectName}
+		if withDependencies {
+			var err error
+			if order, err = utils.DependencyOrder(config.Projects, projectName); err != nil {
+				fmt.Println("Error:", err)
+				return err
+			}
+			if len(order) > 1 {
+				fmt.Printf("Project %s depends on: %s\n", projectName, strings.Join(order[:len(order)-1], ", "))
 			}
 		}
-	} else {
-		action(args[0])
+		for _, name := range order {
+			if _, err := action(name, os.Stdout); err != nil {
+				if name != projectName {
+					fmt.Printf("Dependency %s of project %s failed; not continuing with %s.\n", name, projectName, projectName)
+				}
+				return err
+			}
+		}
+		fmt.Printf(tip, projectName)
+		return nil
+	}
+	if all && !utils.ConfirmAction("Are you sure you want to run for all projects? This may take some time. (yes/no)") {
+		return nil
 	}
+	var dependsOn map[string][]string
+	if withDependencies {
+		// Selected projects bring their dependencies along.
+		if order, err := utils.DependencyOrder(config.Projects, projectNames...); err == nil {
+			projectNames = order
+			sort.Strings(projectNames)
+		}
+		dependsOn = make(map[string][]string, len(config.Projects))
+		for projectName, project := range config.Projects {
+			dependsOn[projectName] = project.DependsOn
+		}
+	}
+	fmt.Printf("Running for %d projects, %d at a time.\n\n", len(projectNames), max(projectJobs, 1))
+	results := utils.RunParallel(projectNames, projectJobs, dependsOn, os.Stdout, action)
+	if failed := printActionSummary(results); failed > 0 {
+		return fmt.Errorf("%d of %d projects failed", failed, len(results))
+	}
+	return nil
+}
+
+// printActionSummary prints a pass/fail table for a multi-project action and
+// returns the number of failed projects.
+func printActionSummary(results []utils.TaskResult) int {
+	withSummary := false
+	for _, result := range results {
+		withSummary = withSummary || result.Summary != ""
+	}
+	header := []string{"Project", "Result", "Time", "Error"}
+	if withSummary {
+		header = []string{"Project", "Result", "Changes", "Time", "Error"}
+	}
+	table := [][]string{header}
+	failed := 0
+	for _, result := range results {
+		status, errText := "ok", ""
+		if result.Err != nil {
+			failed++
+			status = "FAILED"
+			// Only the first sentence; the full message is in the project's output above.
+			errText, _, _ = strings.Cut(result.Err.Error(), "\n")
+			errText, _, _ = strings.Cut(errText, ". ")
+		}
+		row := []string{result.Name, status, result.Duration.Round(100 * time.Millisecond).String(), errText}
+		if withSummary {
+			row = []string{result.Name, status, result.Summary, row[2], errText}
+		}
+		table = append(table, row)
+	}
+	fmt.Println()
+	utils.PrintTable(table)
+	fmt.Printf("\n%d succeeded, %d failed.\n", len(results)-failed, failed)
+	return failed
 }
 
 func listProjects() {
@@ -84,6 +280,9 @@ func listProjects() {
 		return
 	}
 	fmt.Println("Loading projects")
+	if profile := config.ActiveProfile(); profile != utils.DefaultProfileName {
+		fmt.Printf("Profile: %s\n", profile)
+	}
 	if len(config.Projects) == 0 {
 		fmt.Println("No projects in pancake.yml. Run 'pancake edit config' to add one.")
 		return
@@ -105,37 +304,76 @@ func getProject(projectName string) (*utils.Project, bool) {
 	return &project, true
 }
 
-// syncSingleProject synchronizes a single project by name.
-func syncSingleProject(projectName string) {
+// syncSingleProject synchronizes a single project by name, writing progress
+// and the commits it pulled to out. It returns a one-line summary of the change.
+func syncSingleProject(projectName string, out io.Writer) (string, error) {
 	project, ok := getProject(projectName)
 	if !ok {
-		return
+		return "", fmt.Errorf("project %s not found", projectName)
 	}
 
 	projectPath := filepath.Join(config.Home, projectName)
 	gitDirPath := filepath.Join(projectPath, ".git")
+	gitOptions := utils.ProjectGitOptions(*project)
+	gitOptions.AutoStash = syncAutoStash
 	projectExists := utils.CheckExists(projectPath)
 	gitExists := utils.CheckExists(gitDirPath)
 
+	before := ""
 	if !projectExists || !gitExists {
-		fmt.Printf("Syncing... Cloning repository for project %s\n", projectName)
-		if err := utils.CloneRepository(projectPath, project.RemoteSSHURL); err != nil {
-			fmt.Printf("Error syncing project %s: %v\n", projectName, err)
-			return
+		fmt.Fprintf(out, "Syncing... Cloning repository for project %s\n", projectName)
+		if err := utils.CloneRepository(projectPath, project.RemoteSSHURL, gitOptions, out); err != nil {
+			fmt.Fprintf(out, "Error syncing project %s: %v\n", projectName, err)
+			return "", err
 		}
 	} else {
-		fmt.Printf("Syncing... Pulling changes for project %s\n", projectName)
-		if err := utils.PullChanges(projectPath); err != nil {
-			fmt.Printf("Error pulling changes for project %s: %v\n", projectName, err)
-			return
+		before, _ = utils.HeadCommit(projectPath)
+		fmt.Fprintf(out, "Syncing... Pulling changes for project %s\n", projectName)
+		if err := utils.PullChanges(projectPath, gitOptions, out); err != nil {
+			fmt.Fprintf(out, "Error pulling changes for project %s: %v\n", projectName, err)
+			return "", err
 		}
 	}
-	fmt.Printf("Synchronized project %s successfully.\n", projectName)
-	fmt.Printf("\nTip: Run 'pancake open %s' to open the specified project in your preferred IDE.\n", projectName)
+	fmt.Fprintf(out, "Synchronized project %s successfully.\n", projectName)
+	return reportSyncChanges(projectPath, before, out), nil
 }
 
-func syncProjects(args []string) {
-	handleProjectAction(args, syncSingleProject)
+// maxReportedCommits limits how many pulled commits are listed per project.
+const maxReportedCommits = 20
+
+// reportSyncChanges prints the commits between the HEAD before the sync and the current HEAD.
+func reportSyncChanges(projectPath, before string, out io.Writer) string {
+	after, err := utils.HeadCommit(projectPath)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case before == "":
+		fmt.Fprintf(out, "Cloned at %s.\n", utils.ShortCommit(after))
+		return "cloned at " + utils.ShortCommit(after)
+	case before == after:
+		fmt.Fprintf(out, "Already up to date at %s.\n", utils.ShortCommit(after))
+		return "up to date"
+	}
+	commits, err := utils.CommitsBetween(projectPath, before, after)
+	if err != nil || len(commits) == 0 {
+		// Not a fast-forward (e.g. the branch was switched); just show both ends.
+		fmt.Fprintf(out, "HEAD moved from %s to %s.\n", utils.ShortCommit(before), utils.ShortCommit(after))
+		return fmt.Sprintf("%s -> %s", utils.ShortCommit(before), utils.ShortCommit(after))
+	}
+	fmt.Fprintf(out, "Updated %s..%s, %d new commit(s):\n", utils.ShortCommit(before), utils.ShortCommit(after), len(commits))
+	for i, commit := range commits {
+		if i == maxReportedCommits {
+			fmt.Fprintf(out, "  ... and %d more\n", len(commits)-maxReportedCommits)
+			break
+		}
+		fmt.Fprintf(out, "  %s\n", commit)
+	}
+	return fmt.Sprintf("%d new commit(s)", len(commits))
+}
+
+func syncProjects(args []string) error {
+	return handleParallelProjectAction(args, syncSingleProject, false, "\nTip: Run 'pancake open %s' to open the specified project in your preferred IDE.\n")
 }
 
 // openProject opens a project in the configured code editor.
@@ -191,103 +429,587 @@ func pwdProject(args []string) {
 	fmt.Println("Press Ctrl+V to paste and use the command.")
 }
 
-// buildSingleProject builds a single project by name.
-func buildSingleProject(projectName string) {
-	fmt.Printf("Building... Running build command for project %s\n", projectName)
+// projectEnvironment returns the environment for a project's commands:
+// pancake's own environment plus the configured env and env_file variables.
+func projectEnvironment(projectName string) ([]string, error) {
+	overrides, err := utils.ResolveProjectEnv(&config, projectName)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MergeEnv(os.Environ(), overrides), nil
+}
+
+// envProject prints the variables pancake sets for a project, masking secrets.
+func envProject(args []string) {
+	if !loadConfig() {
+		return
+	}
+	if len(args) == 0 {
+		fmt.Println("Usage: pancake env <project_name>")
+		return
+	}
+	projectName := args[0]
+	if _, ok := getProject(projectName); !ok {
+		return
+	}
+	overrides, err := utils.ResolveProjectEnv(&config, projectName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if len(overrides) == 0 {
+		fmt.Printf("Project %s has no env or env_file in pancake.yml; commands inherit your shell's environment.\n", projectName)
+		return
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := overrides[key]
+		if utils.IsSecretEnvKey(key) {
+			value = utils.MaskSecret(value)
+		}
+		fmt.Printf("%s=%s\n", key, value)
+	}
+}
+
+// doProjectTask runs a named task from a project's 'commands' section.
+func doProjectTask(args []string) {
+	if !loadConfig() {
+		return
+	}
+	if len(args) < 2 {
+		fmt.Println("Usage: pancake do <project_name> <task>")
+		if len(args) == 1 {
+			if project, ok := getProject(args[0]); ok {
+				printProjectTasks(args[0], *project)
+			}
+		}
+		return
+	}
+	projectName, task := args[0], args[1]
 	project, ok := getProject(projectName)
 	if !ok {
 		return
 	}
-
 	projectPath := filepath.Join(config.Home, projectName)
 	if !utils.CheckExists(projectPath) {
 		fmt.Printf("Project path %s does not exist.\n", projectPath)
 		fmt.Printf("%s\n", utils.ProjectErrorSync)
 		return
 	}
+	commands, err := utils.ExpandTask(*project, task)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		printProjectTasks(projectName, *project)
+		return
+	}
+	env, err := projectEnvironment(projectName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
-	if project.Build == "" {
-		fmt.Println("Build command not specified in pancake.yml.")
+	for i, command := range commands {
+		fmt.Printf("Step %d/%d of task %s\n", i+1, len(commands), task)
+		if err := utils.ExecuteCommandWithOutput(command, projectPath, env, os.Stdout); err != nil {
+			fmt.Printf("Error running task %s of project %s: step %d failed: %v\n", task, projectName, i+1, err)
+			return
+		}
+	}
+	fmt.Printf("Task %s of project %s finished successfully.\n", task, projectName)
+}
+
+func printProjectTasks(projectName string, project utils.Project) {
+	tasks := utils.TaskNames(project)
+	if len(tasks) == 0 {
+		fmt.Printf("Project %s has no tasks. Add them under 'commands:' in pancake.yml.\n", projectName)
 		fmt.Printf("%s\n", utils.ProjectErrorAddCommand)
 		return
 	}
+	fmt.Printf("Tasks of project %s: %s\n", projectName, strings.Join(tasks, ", "))
+}
 
-	if err := utils.ExecuteCommand(project.Build, projectPath); err != nil {
-		fmt.Printf("Error building project %s: %v\n", projectName, err)
-		return
+// completeProjectTasks completes project names, then the chosen project's task names.
+func completeProjectTasks(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := utils.GetConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	switch len(args) {
+	case 0:
+		return sortedProjectNames(cfg), cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		if project, ok := cfg.Projects[args[0]]; ok {
+			return utils.TaskNames(project), cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProjectNames suggests project names for the first argument.
+func completeProjectNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := utils.GetConfig()
+	if err != nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return sortedProjectNames(cfg), cobra.ShellCompDirectiveNoFileComp
+}
+
+func sortedProjectNames(cfg *utils.Config) []string {
+	projectNames := make([]string, 0, len(cfg.Projects))
+	for projectName := range cfg.Projects {
+		projectNames = append(projectNames, projectName)
+	}
+	sort.Strings(projectNames)
+	return projectNames
+}
+
+// buildSingleProject builds a single project by name, writing progress to out.
+func buildSingleProject(projectName string, out io.Writer) (string, error) {
+	fmt.Fprintf(out, "Building... Running build command for project %s\n", projectName)
+	project, ok := getProject(projectName)
+	if !ok {
+		return "", fmt.Errorf("project %s not found", projectName)
+	}
+
+	projectPath := filepath.Join(config.Home, projectName)
+	if !utils.CheckExists(projectPath) {
+		fmt.Fprintf(out, "Project path %s does not exist.\n", projectPath)
+		fmt.Fprintf(out, "%s\n", utils.ProjectErrorSync)
+		return "", fmt.Errorf("project path %s does not exist", projectPath)
+	}
+
+	if project.Build == "" {
+		fmt.Fprintln(out, "Build command not specified in pancake.yml.")
+		fmt.Fprintf(out, "%s\n", utils.ProjectErrorAddCommand)
+		return "", fmt.Errorf("build command not specified")
+	}
+
+	env, err := projectEnvironment(projectName)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return "", err
+	}
+	if err := utils.ExecuteCommandWithOutput(project.Build, projectPath, env, out); err != nil {
+		fmt.Fprintf(out, "Error building project %s: %v\n", projectName, err)
+		return "", err
 	}
-	fmt.Printf("Built project %s successfully.\n", projectName)
-	fmt.Printf("\nTip: Run 'pancake run %s' to start the project locally.\n", projectName)
+	fmt.Fprintf(out, "Built project %s successfully.\n", projectName)
+	return "", nil
 }
 
 func buildProject(args []string) {
-	handleProjectAction(args, buildSingleProject)
+	_ = handleParallelProjectAction(args, buildSingleProject, true, "\nTip: Run 'pancake run %s' to start the project locally.\n")
 }
 
-// runSingleProject runs a single project by name.
+// runSingleProject runs a single project by name, starting the projects it
+// depends on first.
 func runSingleProject(projectName string) {
+	if _, ok := getProject(projectName); !ok {
+		return
+	}
+	order, err := utils.DependencyOrder(config.Projects, projectName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := utils.LoadProjectProcesses(config.Home, &projectProcesses); err != nil {
+		fmt.Printf("Warning: could not load project PIDs: %v\n", err)
+	}
+	for _, dependency := range order[:len(order)-1] {
+		if record, exists := projectProcesses[dependency]; exists {
+			if status, _ := utils.CheckProcess(record); status == utils.ProcessRunning {
+				fmt.Printf("Dependency %s of project %s is already running.\n", dependency, projectName)
+				continue
+			}
+		}
+		fmt.Printf("Starting dependency %s of project %s\n", dependency, projectName)
+		if !startProject(dependency) {
+			fmt.Printf("Dependency %s of project %s did not start; not starting %s.\n", dependency, projectName, projectName)
+			return
+		}
+	}
+	startProject(projectName)
+}
+
+// startProject starts a single project and records its process. It reports
+// whether the project was started.
+func startProject(projectName string) bool {
 	fmt.Printf("Running project %s\n", projectName)
 	project, ok := getProject(projectName)
 	if !ok {
-		return
+		return false
 	}
 
 	projectPath := filepath.Join(config.Home, projectName)
 	if !utils.CheckExists(projectPath) {
 		fmt.Printf("Project path %s does not exist.\n", projectPath)
 		fmt.Printf("%s\n", utils.ProjectErrorAddConfig)
-		return
+		return false
 	}
 
 	if project.Run == "" {
 		fmt.Println("Run command not specified in pancake.yml.")
 		fmt.Printf("%s\n", utils.ProjectErrorAddCommand)
-		return
+		return false
 	}
 
-	if err := utils.ExecuteCommandInNewTerminal(project.Run, projectPath, projectName, &projectPIDs); err != nil {
-		fmt.Printf("Error running project %s: %v\n", projectName, err)
-		return
+	// Keep projects started by earlier invocations when pids.json is rewritten.
+	if err := utils.LoadProjectProcesses(config.Home, &projectProcesses); err != nil {
+		fmt.Printf("Warning: could not load project PIDs: %v\n", err)
+	}
+	if record, exists := projectProcesses[projectName]; exists {
+		if status, _ := utils.CheckProcess(record); status == utils.ProcessRunning {
+			fmt.Printf("Project %s is already running (PID %d).\n", projectName, record.PID)
+			fmt.Printf("\nTip: Run 'pancake restart %s' to restart it.\n", projectName)
+			return false
+		}
+	}
+
+	if !checkProjectPort(projectName, project) {
+		return false
+	}
+	env, err := projectEnvironment(projectName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return false
 	}
-	fmt.Printf("Started project %s successfully.\n", projectName)
-	if err := utils.SaveProjectPIDs(config.Home, projectPIDs); err != nil {
+
+	var pid int
+	if runInTerminal {
+		if pid, err = utils.ExecuteCommandInNewTerminal(project.Run, projectPath, projectName, env); err != nil {
+			fmt.Printf("Error running project %s: %v\n", projectName, err)
+			return false
+		}
+		fmt.Printf("Started project %s successfully.\n", projectName)
+	} else {
+		logPath := utils.ProjectLogPath(config.Home, projectName)
+		if pid, err = utils.StartDetachedProcess(project.Run, projectPath, logPath, env); err != nil {
+			fmt.Printf("Error running project %s: %v\n", projectName, err)
+			return false
+		}
+		fmt.Printf("Started project %s in the background (PID %d).\n", projectName, pid)
+		fmt.Printf("Output is written to %s\n", logPath)
+	}
+	record := utils.NewProcessRecord(pid)
+	projectProcesses[projectName] = record
+	if processes, err := utils.UpdateProjectProcesses(config.Home, func(processes map[string]utils.ProcessRecord) {
+		processes[projectName] = record
+	}); err != nil {
 		fmt.Printf("Warning: could not save project PIDs: %v\n", err)
+	} else {
+		projectProcesses = processes
+	}
+	return waitUntilHealthy(projectName, project, projectPath, env, pid)
+}
+
+// waitUntilHealthy blocks until a project with a health section passes its
+// checks, so that dependents only start once it is ready.
+func waitUntilHealthy(projectName string, project *utils.Project, projectPath string, env []string, pid int) bool {
+	if project.Health == nil {
+		return true
+	}
+	fmt.Printf("Waiting up to %s for project %s to become healthy...\n", project.Health.TimeoutDuration(), projectName)
+	var alive func() bool
+	if !runInTerminal {
+		// The terminal's PID says nothing about the project, so only detached runs are watched.
+		alive = func() bool { return utils.ProcessAlive(pid) }
+	}
+	if err := utils.WaitForHealthy(*project.Health, projectPath, env, alive); err != nil {
+		fmt.Printf("Project %s is not healthy: %v\n", projectName, err)
+		fmt.Printf("Run 'pancake logs %s' to see its output.\n", projectName)
+		return false
 	}
+	fmt.Printf("Project %s is healthy.\n", projectName)
+	return true
 }
 
 func runProject(args []string) {
 	handleProjectAction(args, runSingleProject)
 }
 
-func monitorProject() {
-	if !loadConfig() {
+// checkProjectPort makes sure nothing else is listening on the project's port before it starts.
+func checkProjectPort(projectName string, project *utils.Project) bool {
+	if project.Port == "" {
+		return true
+	}
+	port, err := utils.ParsePort(project.Port)
+	if err != nil {
+		fmt.Printf("Warning: skipping port check for project %s: %v\n", projectName, err)
+		return true
+	}
+	listener, err := utils.FindPortListener(port)
+	if err != nil {
+		fmt.Printf("Warning: could not check port %d: %v\n", port, err)
+		return true
+	}
+	if listener == nil {
+		return true
+	}
+
+	owner := "another process"
+	if listener.PID != 0 {
+		owner = fmt.Sprintf("PID %d", listener.PID)
+		if stats, err := utils.InspectProcess(listener.PID); err == nil && stats.Cmdline != "" {
+			owner = fmt.Sprintf("PID %d (%s)", listener.PID, stats.Cmdline)
+		}
+	}
+	if runForce {
+		fmt.Printf("Warning: port %d for project %s is already in use by %s. Starting anyway (--force).\n", port, projectName, owner)
+		return true
+	}
+	fmt.Printf("Port %d for project %s is already in use by %s.\n", port, projectName, owner)
+	fmt.Printf("%s\n", utils.ProjectErrorPortInUse)
+	return false
+}
+
+// portStatus describes whether a project is listening on its declared port.
+func portStatus(project utils.Project, record utils.ProcessRecord, running bool) string {
+	if project.Port == "" {
+		return "-"
+	}
+	port, err := utils.ParsePort(project.Port)
+	if err != nil {
+		return "invalid port"
+	}
+	listener, err := utils.FindPortListener(port)
+	if err != nil {
+		return "unknown"
+	}
+	switch {
+	case listener == nil:
+		return "No"
+	case running && (listener.PID == 0 || utils.ListenerBelongsTo(listener, record.PID)):
+		return "Yes"
+	case listener.PID != 0:
+		return fmt.Sprintf("In use by PID %d", listener.PID)
+	default:
+		return "In use"
+	}
+}
+
+// stopRunningProject stops the process tree of a running project and removes it from pids.json.
+func stopRunningProject(projectName string) bool {
+	if err := utils.LoadProjectProcesses(config.Home, &projectProcesses); err != nil {
+		fmt.Printf("Error: could not load project PIDs: %v\n", err)
+		return false
+	}
+	record, exists := projectProcesses[projectName]
+	if !exists {
+		fmt.Printf("Project %s is not running.\n", projectName)
+		return true
+	}
+
+	// Never signal a PID that has since been reused by an unrelated process.
+	if status, _ := utils.CheckProcess(record); status != utils.ProcessRunning {
+		fmt.Printf("Project %s is not running (PID %d is %s).\n", projectName, record.PID, strings.ToLower(status))
+	} else {
+		fmt.Printf("Stopping project %s (PID %d)\n", projectName, record.PID)
+		if err := utils.StopProcessTree(record.PID, stopGracePeriod); err != nil {
+			fmt.Printf("Error stopping project %s: %v\n", projectName, err)
+			return false
+		}
+		fmt.Printf("Stopped project %s.\n", projectName)
+	}
+	delete(projectProcesses, projectName)
+	if processes, err := utils.UpdateProjectProcesses(config.Home, func(processes map[string]utils.ProcessRecord) {
+		if processes[projectName] == record {
+			delete(processes, projectName)
+		}
+	}); err != nil {
+		fmt.Printf("Warning: could not save project PIDs: %v\n", err)
+	} else {
+		projectProcesses = processes
+	}
+	return true
+}
+
+// stopSingleProject stops a single project by name.
+func stopSingleProject(projectName string) {
+	if _, ok := getProject(projectName); !ok {
 		return
 	}
-	fmt.Println("Monitoring... Fetching project status")
-	if err := utils.LoadProjectPIDs(config.Home, &projectPIDs); err != nil {
-		fmt.Printf("Warning: could not load project PIDs: %v\n", err)
+	stopRunningProject(projectName)
+}
+
+// stopProject stops the selected projects in reverse dependency order, so
+// no project loses a dependency while it is still running.
+func stopProject(args []string) {
+	projectNames, ok := selectProjectsInOrder(args)
+	if !ok {
+		return
 	}
+	slices.Reverse(projectNames)
+	for _, projectName := range projectNames {
+		stopSingleProject(projectName)
+	}
+}
 
-	data := [][]string{
-		{"Project Name", "Running", "PID", "Port", "Type"},
+// restartSingleProject stops a single project, if it is running, and starts it again.
+func restartSingleProject(projectName string) {
+	if _, ok := getProject(projectName); !ok {
+		return
 	}
+	if !stopRunningProject(projectName) {
+		return
+	}
+	runSingleProject(projectName)
+}
 
-	for projectName, project := range config.Projects {
-		running := "No"
-		pid := "-"
+// restartProject stops the selected projects in reverse dependency order and
+// then starts them in dependency order. A project that could not be stopped
+// is not started again.
+func restartProject(args []string) {
+	projectNames, ok := selectProjectsInOrder(args)
+	if !ok {
+		return
+	}
+	stopped := make(map[string]bool)
+	for i := len(projectNames) - 1; i >= 0; i-- {
+		if _, ok := getProject(projectNames[i]); ok {
+			stopped[projectNames[i]] = stopRunningProject(projectNames[i])
+		}
+	}
+	for _, projectName := range projectNames {
+		if stopped[projectName] {
+			runSingleProject(projectName)
+		}
+	}
+}
+
+var monitorHeader = []string{"Project Name", "Status", "PID", "Uptime", "Memory", "CPU", "Port", "Listening", "Health", "Type"}
+
+// collectProjectStatus builds one monitor row per project, sorted by name, and
+// removes pids.json entries whose process is gone. It returns the rows and a
+// description of every pruned entry.
+func collectProjectStatus() ([][]string, []string) {
+	projectProcesses = make(map[string]utils.ProcessRecord)
+	if err := utils.LoadProjectProcesses(config.Home, &projectProcesses); err != nil {
+		fmt.Printf("Warning: could not load project PIDs: %v\n", err)
+	}
+
+	projectNames := make([]string, 0, len(config.Projects))
+	for projectName := range config.Projects {
+		projectNames = append(projectNames, projectName)
+	}
+	sort.Strings(projectNames)
+
+	var rows [][]string
+	var pruned []string
+	prunedRecords := make(map[string]utils.ProcessRecord)
+	probed := make(map[int]string) // row -> running project with a health section
+	for _, projectName := range projectNames {
+		project := config.Projects[projectName]
+		status := utils.ProcessStopped
+		pid, uptime, memory, cpu := "-", "-", "-", "-"
 		port := project.Port
 		projectType := project.Type
 
-		if pidVal, exists := projectPIDs[projectName]; exists {
-			running = "Yes"
-			pid = fmt.Sprintf("%d", pidVal)
+		record, exists := projectProcesses[projectName]
+		if exists {
+			var stats *utils.ProcessStats
+			status, stats = utils.CheckProcess(record)
+			if status == utils.ProcessRunning {
+				pid = fmt.Sprintf("%d", record.PID)
+				if stats != nil {
+					if !stats.StartedAt.IsZero() {
+						uptime = utils.FormatUptime(time.Since(stats.StartedAt))
+					}
+					if stats.MemoryBytes > 0 {
+						memory = utils.FormatBytes(stats.MemoryBytes)
+					}
+					cpu = fmt.Sprintf("%.1f%%", stats.CPUPercent)
+				}
+			} else {
+				pruned = append(pruned, fmt.Sprintf("%s (PID %d, %s)", projectName, record.PID, strings.ToLower(status)))
+				prunedRecords[projectName] = record
+				delete(projectProcesses, projectName)
+				status = utils.ProcessStopped
+			}
 		}
+		listening := portStatus(project, record, status == utils.ProcessRunning)
 		if port == "" {
 			port = "-"
 		}
+		if project.Health != nil && status == utils.ProcessRunning {
+			probed[len(rows)] = projectName
+		}
+
+		rows = append(rows, []string{projectName, status, pid, uptime, memory, cpu, port, listening, "-", projectType})
+	}
+	checkProjectHealth(rows, probed)
+
+	// Entries for projects that were removed from pancake.yml are pruned too once they are dead.
+	for projectName, record := range projectProcesses {
+		if _, exists := config.Projects[projectName]; exists {
+			continue
+		}
+		if status, _ := utils.CheckProcess(record); status != utils.ProcessRunning {
+			pruned = append(pruned, fmt.Sprintf("%s (PID %d, %s)", projectName, record.PID, strings.ToLower(status)))
+			prunedRecords[projectName] = record
+			delete(projectProcesses, projectName)
+		}
+	}
+
+	if len(pruned) > 0 {
+		// Only drop the exact records found dead: another pancake process
+		// may have started the project again since pids.json was read.
+		if _, err := utils.UpdateProjectProcesses(config.Home, func(processes map[string]utils.ProcessRecord) {
+			for projectName, record := range prunedRecords {
+				if processes[projectName] == record {
+					delete(processes, projectName)
+				}
+			}
+		}); err != nil {
+			fmt.Printf("Warning: could not save project PIDs: %v\n", err)
+			return rows, nil
+		}
+	}
+	return rows, pruned
+}
 
-		data = append(data, []string{projectName, running, pid, port, projectType})
+// checkProjectHealth fills in the Health column of the monitor rows of the
+// projects in probed. The probes run at the same time, so a project whose
+// probe hangs until its timeout does not hold up the others.
+func checkProjectHealth(rows [][]string, probed map[int]string) {
+	var wg sync.WaitGroup
+	for row, projectName := range probed {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health := utils.HealthHealthy
+			env, err := projectEnvironment(projectName)
+			if err != nil {
+				health = "unknown"
+			} else if utils.CheckHealth(*config.Projects[projectName].Health, filepath.Join(config.Home, projectName), env) != nil {
+				health = utils.HealthUnhealthy
+			}
+			rows[row][8] = health
+		}()
 	}
+	wg.Wait()
+}
 
-	utils.PrintTable(data)
+func monitorProject() {
+	if !loadConfig() {
+		return
+	}
+	if monitorWatch {
+		watchProjects(monitorInterval)
+		return
+	}
+	fmt.Println("Monitoring... Fetching project status")
+	rows, pruned := collectProjectStatus()
+	utils.PrintTable(append([][]string{monitorHeader}, rows...))
+
+	if len(pruned) > 0 {
+		fmt.Println("\nRemoved stale entries from pids.json:")
+		for _, entry := range pruned {
+			fmt.Printf("- %s\n", entry)
+		}
+	}
+	fmt.Println("\nTip: Run 'pancake monitor --watch' for a live dashboard.")
 }
@@ -18,6 +18,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/a6h15hek/pancake/utils"
 	"github.com/spf13/cobra"
@@ -84,6 +85,9 @@ func initCommand(force bool) error {
 			}
 			fmt.Printf("Backed up existing pancake.yml to %s\n", backup)
 		}
+		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+			return fmt.Errorf("could not create the directory for pancake.yml at %s: %w", configPath, err)
+		}
 		if err := os.WriteFile(configPath, []byte(utils.DefaultYMLContent), 0644); err != nil {
 			return fmt.Errorf("could not create pancake.yml at %s: %w", configPath, err)
 		}
@@ -110,6 +114,9 @@ func initCommand(force bool) error {
 }
 
 func Execute() {
+	if utils.SupervisorRequested() {
+		os.Exit(utils.RunSupervisor())
+	}
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -117,6 +124,8 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().StringVar(&utils.ConfigPathOverride, "config", "", "Config file to use instead of the one found by 'pancake config path'")
+	rootCmd.PersistentFlags().StringVar(&utils.ProfileOverride, "profile", "", "Profile from 'profiles:' to use instead of the active one")
 
 	initCmd := &cobra.Command{
 		Use: "init",
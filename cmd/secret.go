@@ -0,0 +1,127 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// secretCmd manages the encrypted local secrets file that AI API keys are
+// read from when pancake.yml does not provide them.
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Store API keys in an encrypted local file instead of pancake.yml.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	setCmd := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Store a secret, e.g. 'gemini' or 'chatgpt' for their API keys. The value is read from stdin.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return setSecret(args[0]) })
+		},
+	}
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the names of the stored secrets.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(listSecrets)
+		},
+	}
+	removeCmd := &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a stored secret.",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runConfigCommand(func() error { return removeSecret(args[0]) })
+		},
+	}
+	secretCmd.AddCommand(setCmd, listCmd, removeCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+func setSecret(name string) error {
+	var value string
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Printf("Value for %s (input is hidden): ", name)
+		input, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("could not read the secret: %w", err)
+		}
+		value = string(input)
+	} else {
+		// Piped, e.g. 'pass show openai | pancake secret set chatgpt'.
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("could not read the secret: %w", err)
+		}
+		value = string(input)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("no value given for secret '%s'", name)
+	}
+	if err := utils.SetSecret(name, value); err != nil {
+		return err
+	}
+	secretsPath, _ := utils.SecretsPath()
+	fmt.Printf("Stored secret %s in %s.\n", name, secretsPath)
+	return nil
+}
+
+func listSecrets() error {
+	secrets, err := utils.LoadSecrets()
+	if err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		fmt.Println("No secrets stored. Run 'pancake secret set <name>' to add one.")
+		return nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(secrets)) {
+		fmt.Printf("- %s\n", name)
+	}
+	return nil
+}
+
+func removeSecret(name string) error {
+	existed, err := utils.DeleteSecret(name)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		return fmt.Errorf("no secret named '%s'", name)
+	}
+	fmt.Printf("Removed secret %s.\n", name)
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 Abhishek M. Yadav <abhishekyadav@duck.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/a6h15hek/pancake/utils"
+	"github.com/spf13/cobra"
+)
+
+var statusFetch bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status [project_name...]",
+	Short: "Show the git state of every project: branch, uncommitted changes, ahead/behind and last commit.",
+	Run: func(cmd *cobra.Command, args []string) {
+		showStatus(args)
+	},
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusFetch, "fetch", false, "Fetch every project's remote first so ahead/behind counts are up to date")
+
+	projectCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(statusCmd)
+}
+
+func showStatus(args []string) {
+	if !loadConfig() {
+		return
+	}
+	projectNames := args
+	if len(projectNames) == 0 {
+		for projectName := range config.Projects {
+			projectNames = append(projectNames, projectName)
+		}
+		if len(projectNames) == 0 {
+			fmt.Println("No projects in pancake.yml. Run 'pancake edit config' to add one.")
+			return
+		}
+	} else {
+		for _, projectName := range projectNames {
+			if _, ok := getProject(projectName); !ok {
+				return
+			}
+		}
+	}
+	sort.Strings(projectNames)
+
+	var checkouts []string
+	for _, projectName := range projectNames {
+		if utils.CheckExists(filepath.Join(config.Home, projectName, ".git")) {
+			checkouts = append(checkouts, projectName)
+		}
+	}
+	if statusFetch && len(checkouts) > 0 {
+		fmt.Println("Fetching remotes...")
+		results := utils.RunParallel(checkouts, 8, nil, io.Discard, func(projectName string, out io.Writer) (string, error) {
+			return "", utils.FetchRemotes(filepath.Join(config.Home, projectName))
+		})
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Printf("Warning: %v\n", result.Err)
+			}
+		}
+	}
+
+	table := [][]string{{"Project Name", "Branch", "Changes", "Ahead", "Behind", "Last Commit"}}
+	for _, projectName := range projectNames {
+		table = append(table, projectStatusRow(projectName))
+	}
+	utils.PrintTable(table)
+	if !statusFetch {
+		fmt.Println("\nTip: Run 'pancake status --fetch' to compare against the latest state of the remotes.")
+	}
+}
+
+func projectStatusRow(projectName string) []string {
+	projectPath := filepath.Join(config.Home, projectName)
+	if !utils.CheckExists(projectPath) {
+		return []string{projectName, "not synced", "-", "-", "-", "-"}
+	}
+	if !utils.CheckExists(filepath.Join(projectPath, ".git")) {
+		return []string{projectName, "not a git repository", "-", "-", "-", "-"}
+	}
+	status, err := utils.ReadGitStatus(projectPath)
+	if err != nil {
+		return []string{projectName, "error: " + err.Error(), "-", "-", "-", "-"}
+	}
+
+	branch := status.Branch
+	if branch == "" {
+		branch = "(detached)"
+	}
+	changes := "clean"
+	switch {
+	case status.Dirty:
+		changes = "uncommitted"
+	case status.Untracked:
+		changes = "untracked files"
+	}
+	ahead, behind := "-", "-"
+	if status.HasUpstream {
+		ahead, behind = fmt.Sprintf("%d", status.Ahead), fmt.Sprintf("%d", status.Behind)
+	}
+	lastCommit := "-"
+	if !status.LastCommit.IsZero() {
+		lastCommit = utils.FormatUptime(time.Since(status.LastCommit)) + " ago"
+	}
+	return []string{projectName, branch, changes, ahead, behind, lastCommit}
+}
@@ -18,6 +18,7 @@ package cmd
 import (
 	"fmt"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/a6h15hek/pancake/utils"
@@ -54,6 +55,9 @@ func listTools() {
 		fmt.Println(utils.ConfigHintEditConfig)
 		return
 	}
+	if profile := cfg.ActiveProfile(); profile != utils.DefaultProfileName {
+		fmt.Printf("Profile: %s\n", profile)
+	}
 	if len(cfg.Tools) == 0 {
 		fmt.Println("No tools listed in pancake.yml. Run 'pancake tool install <name>' to add one.")
 		return
@@ -153,26 +157,21 @@ func handleToolCommand(args []string, action string) {
 		return
 	}
 
-	switch action {
-	case "install":
-		cfg.Tools = append(cfg.Tools, toolName)
-		if err := utils.UpdateConfig(cfg); err != nil {
-			fmt.Println("Error updating pancake.yml:", err)
-		} else {
-			fmt.Println("Config file updated successfully.")
-		}
-	case "uninstall":
-		for i, existing := range cfg.Tools {
-			if existing == toolName {
-				cfg.Tools = append(cfg.Tools[:i], cfg.Tools[i+1:]...)
-				if err := utils.UpdateConfig(cfg); err != nil {
-					fmt.Println("Error updating pancake.yml:", err)
-				} else {
-					fmt.Println("Config file updated successfully.")
-				}
-				break
-			}
+	if action != "install" && action != "uninstall" {
+		return
+	}
+	err = utils.ModifyConfig(func(cfg *utils.Config) error {
+		tools := slices.DeleteFunc(cfg.Tools, func(existing string) bool { return existing == toolName })
+		if action == "install" {
+			tools = append(tools, toolName)
 		}
+		cfg.Tools = tools
+		return nil
+	})
+	if err != nil {
+		fmt.Println("Error updating pancake.yml:", err)
+	} else {
+		fmt.Println("Config file updated successfully.")
 	}
 }
 
@@ -444,6 +444,7 @@ uninstall_pancake() {
         if [[ -f "$config_file" ]]; then
             rm -f "$config_file" && log "Removed $config_file"
         fi
+        rm -f "${config_file}.lock"
         if [[ -d "$pancake_home" ]]; then
             if confirm "Also remove pancake project directory ${pancake_home}? This deletes all synced projects."; then
                 rm -rf "$pancake_home" && log "Removed $pancake_home"
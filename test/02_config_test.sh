@@ -1,8 +1,8 @@
 #!/usr/bin/env bash
 # 02 — config validation & troubleshooting messages
 # Covers: missing config, unparseable YAML, empty home, relative home, bad
-# default_ai, unsafe project name, project missing remote_ssh_url. Every failure
-# must point the developer to fix pancake.yml (not pancake itself).
+# default_ai, unsafe project name, project missing remote_ssh_url, includes.
+# Every failure must point the developer to fix pancake.yml (not pancake itself).
 
 set -uo pipefail
 source "$(dirname "$0")/helpers.sh"
@@ -105,6 +105,182 @@ assert_exit_code 0 "valid config loads" run_pancake project list
 assert_contains "valid config lists project" "demo" run_pancake project list
 cleanup_mock_home
 
+# Included files are merged under the local config, which wins.
+setup_mock_home
+cat > "$MOCK_HOME/team.yml" <<'YAML'
+code_editor: idea .
+projects:
+  shared:
+    remote_ssh_url: git@github.com:org/shared.git
+YAML
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+include: team.yml
+home: $HOME/pancake
+code_editor: echo
+projects:
+  demo:
+    remote_ssh_url: git@github.com:org/repo.git
+YAML
+assert_contains "included project is listed" "shared" run_pancake project list
+assert_contains "resolved config names the origin" "from team.yml:4" run_pancake config show --resolved
+assert_contains "local value wins over the include" "code_editor: echo" run_pancake config show --resolved
+cleanup_mock_home
+
+# A missing include is reported against pancake.yml.
+setup_mock_home
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+include: missing.yml
+home: $HOME/pancake
+projects: {}
+YAML
+assert_contains "missing include -> mentions include" "include" run_pancake project list
+cleanup_mock_home
+
+# A config without 'version' is shown as a diff by a dry run, then upgraded
+# with a backup of the original.
+setup_mock_home
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+home: $HOME/pancake # where projects live
+projects: {}
+YAML
+assert_contains "migrate dry run shows the diff" "+version: 1" run_pancake config migrate --dry-run
+assert_file_missing "dry run writes no backup" "$MOCK_HOME/pancake.yml.v0.bak"
+assert_contains "old version -> validate points to migrate" "pancake config migrate" run_pancake config validate
+run_pancake project list >/dev/null 2>&1
+assert_file_missing "list does not upgrade the config" "$MOCK_HOME/pancake.yml.v0.bak"
+assert_exit_code 0 "migrate upgrades the config" run_pancake config migrate
+assert_file_contains "migrated config has a version" "$MOCK_HOME/pancake.yml" "version: 1"
+assert_file_contains "migrated config keeps comments" "$MOCK_HOME/pancake.yml" "# where projects live"
+assert_file_exists "migrate writes a backup" "$MOCK_HOME/pancake.yml.v0.bak"
+assert_contains "migrate is idempotent" "already at version 1" run_pancake config migrate
+cleanup_mock_home
+
+# A config written by a newer pancake is refused.
+setup_mock_home
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+version: 99
+home: $HOME/pancake
+projects: {}
+YAML
+assert_contains "newer version -> asks to upgrade pancake" "Upgrade pancake" run_pancake project list
+cleanup_mock_home
+
+# config validate reports each issue with its position, and the schema is JSON.
+setup_mock_home
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+version: 1
+home: $HOME/pancake
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    biuld: make
+    port: 3000
+  web:
+    remote_ssh_url: git@github.com:org/web.git
+    port: 3000
+YAML
+assert_contains "validate points at the typo" "pancake.yml:6:5: warning: unknown config field 'projects.api.biuld' (did you mean 'build'?)" run_pancake config validate
+assert_contains "validate warns about duplicate ports" "same 'port' as project 'api'" run_pancake config validate
+assert_exit_code 0 "validate passes with only warnings" run_pancake config validate
+assert_exit_code 1 "validate --strict fails on warnings" run_pancake config validate --strict
+sed -i.orig 's/port: 3000$/port: abc/' "$MOCK_HOME/pancake.yml"
+assert_contains "validate warns about a port that is not a number" "'port' that is not a number" run_pancake config validate
+assert_exit_code 0 "list loads a config with such a port" run_pancake project list
+sed -i.orig 's|remote_ssh_url: git@github.com:org/web.git|remote_ssh_url: ""|' "$MOCK_HOME/pancake.yml"
+assert_exit_code 1 "validate fails on errors" run_pancake config validate
+assert_contains "schema is a JSON Schema" '"$schema": "http://json-schema.org/draft-07/schema#"' run_pancake config schema
+cleanup_mock_home
+
+# The config file is found through --config, PANCAKE_CONFIG, a project-local
+# .pancake.yml and $XDG_CONFIG_HOME, falling back to $HOME/pancake.yml.
+setup_mock_home
+write_named_config() {
+    mkdir -p "$(dirname "$1")"
+    cat > "$1" <<YAML
+version: 1
+home: \$HOME/pancake
+projects:
+  $2:
+    remote_ssh_url: git@github.com:org/$2.git
+YAML
+}
+write_named_config "$MOCK_HOME/pancake.yml" home_project
+write_named_config "$XDG_CONFIG_HOME/pancake/pancake.yml" xdg_project
+write_named_config "$MOCK_HOME/work/.pancake.yml" local_project
+write_named_config "$MOCK_HOME/env.yml" env_project
+write_named_config "$MOCK_HOME/flag.yml" flag_project
+mkdir -p "$MOCK_HOME/work/sub"
+assert_contains "XDG config is preferred over ~/pancake.yml" "xdg_project" run_pancake project list
+assert_contains "project-local .pancake.yml is found from a subdirectory" "local_project" bash -c "cd '$MOCK_HOME/work/sub' && '$PANCAKE_BIN' project list"
+assert_contains "PANCAKE_CONFIG wins over the lookup" "env_project" env PANCAKE_CONFIG="$MOCK_HOME/env.yml" "$PANCAKE_BIN" project list
+assert_contains "--config wins over PANCAKE_CONFIG" "flag_project" env PANCAKE_CONFIG="$MOCK_HOME/env.yml" "$PANCAKE_BIN" --config "$MOCK_HOME/flag.yml" project list
+assert_contains "config path explains the choice" "(XDG_CONFIG_HOME)" run_pancake config path
+rm "$XDG_CONFIG_HOME/pancake/pancake.yml"
+assert_contains "falls back to ~/pancake.yml" "home_project" run_pancake project list
+assert_exit_code 0 "init creates the --config file" run_pancake --config "$MOCK_HOME/ci/pancake.yml" init
+assert_file_exists "init wrote the --config file" "$MOCK_HOME/ci/pancake.yml"
+cleanup_mock_home
+
+# Profiles: commands work on the active profile and write to it.
+setup_mock_home
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+version: 1
+home: $HOME/pancake
+projects:
+  personal:
+    remote_ssh_url: git@github.com:me/personal.git
+profiles:
+  acme:
+    home: $HOME/acme
+    projects:
+      portal:
+        remote_ssh_url: git@github.com:acme/portal.git
+YAML
+assert_contains "top-level projects without a profile" "personal" run_pancake project list
+assert_exit_code 0 "profile use switches profile" run_pancake profile use acme
+assert_file_contains "active profile is recorded" "$MOCK_HOME/pancake.yml" "profile: acme"
+assert_contains "list shows the profile's projects" "portal" run_pancake project list
+assert_contains "profile shows its home" "$MOCK_HOME/acme" run_pancake profile
+assert_contains "--profile selects another profile once" "personal" run_pancake --profile default project list
+assert_exit_code 0 "project add writes to the active profile" run_pancake project add git@github.com:acme/billing.git
+assert_contains "added project is in the profile" "billing" run_pancake project list
+assert_exit_code 1 "unknown profile is refused" run_pancake profile use nope
+assert_contains "unknown --profile lists profiles" "Available profiles: default, acme" run_pancake --profile nope project list
+cleanup_mock_home
+
+# Secrets: stored encrypted outside pancake.yml, and stripped by config share.
+setup_mock_home
+cat > "$MOCK_HOME/pancake.yml" <<'YAML'
+version: 1
+home: $HOME/pancake
+chatgpt:
+  api_key: "sk-live-123" # personal key
+  api_key_env: OPENAI_API_KEY
+env:
+  GITHUB_TOKEN: ghp_123
+projects: {}
+YAML
+assert_exit_code 0 "secret set reads the value from stdin" bash -c "echo gemini-key | '$PANCAKE_BIN' secret set gemini"
+assert_contains "secret list shows the name" "- gemini" run_pancake secret list
+assert_file_exists "secrets file is written" "$XDG_CONFIG_HOME/pancake/secrets.enc"
+if grep -q "gemini-key" "$XDG_CONFIG_HOME/pancake/secrets.enc"; then
+    fail "secrets file is encrypted" "no plaintext key" "found gemini-key"
+else
+    pass "secrets file is encrypted"
+fi
+assert_exit_code 0 "secret remove deletes it" run_pancake secret rm gemini
+assert_exit_code 1 "secret remove fails for a missing secret" run_pancake secret rm gemini
+assert_contains "config share keeps the key's comment" 'api_key: "" # personal key' run_pancake config share
+assert_contains "config share keeps api_key_env" "api_key_env: OPENAI_API_KEY" run_pancake config share
+assert_contains "config share blanks credential env values" 'GITHUB_TOKEN: ""' run_pancake config share
+assert_exit_code 0 "config share writes a file" run_pancake config share -o "$MOCK_HOME/shared.yml"
+if grep -q "sk-live-123" "$MOCK_HOME/shared.yml"; then
+    fail "shared file has no API key" "no sk-live-123" "found it"
+else
+    pass "shared file has no API key"
+fi
+cleanup_mock_home
+
 print_summary
 RESULT=$?
 rm -f /tmp/pancake_test_out
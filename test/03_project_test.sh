@@ -1,8 +1,11 @@
 #!/usr/bin/env bash
 # 03 — project commands edge cases
 # Covers: list empty / populated, sync into non-existent dir (mkdir), sync
-# refuses to clobber a non-git dir, open / build / run / pwd for missing project,
-# monitor table renders, project name with slash is rejected upstream.
+# refuses to clobber a non-git dir (exit 1), re-sync reports up to date, sync of
+# all projects prints a summary and exits 1 on failure,
+# open / build / run / pwd for missing project,
+# monitor table renders, project name with slash is rejected upstream,
+# import of existing checkouts, add / rename / remove.
 
 set -uo pipefail
 source "$(dirname "$0")/helpers.sh"
@@ -71,6 +74,7 @@ cleanup_mock_home
 write_valid_config
 assert_contains "populated list shows demo" "demo" run_pancake project list
 assert_contains "populated list shows webapp" "webapp" run_pancake project list
+assert_contains "logs -f without logs returns" "No project logs yet" run_pancake logs -f
 cleanup_mock_home
 
 # sync of a missing project -> not found message (no crash).
@@ -84,11 +88,25 @@ assert_exit_code 0 "sync real project clones" run_pancake project sync demo
 assert_file_exists "cloned project dir created" "$MOCK_HOME/pancake/demo/.git"
 cleanup_mock_home
 
+# sync of an up-to-date checkout reports it and exits 0.
+write_valid_config
+run_pancake project sync demo >/dev/null 2>&1
+assert_contains "re-sync reports up to date" "Already up to date" run_pancake project sync demo
+cleanup_mock_home
+
+# sync of all projects with one failing -> summary and exit 1.
+write_valid_config
+mkdir -p "$MOCK_HOME/pancake/webapp"
+echo "important-user-data" > "$MOCK_HOME/pancake/webapp/important.txt"
+assert_exit_code 1 "sync all with a failure exits 1" bash -c "echo yes | '$PANCAKE_BIN' project sync"
+assert_contains "sync all prints summary" "1 succeeded, 1 failed" bash -c "echo yes | '$PANCAKE_BIN' project sync"
+cleanup_mock_home
+
 # sync refuses to clobber a non-git directory (user data protection).
 write_valid_config
 mkdir -p "$MOCK_HOME/pancake/demo"
 echo "important-user-data" > "$MOCK_HOME/pancake/demo/important.txt"
-assert_exit_code 0 "sync refuses to clobber (exit 0 from pancake, git fails)" run_pancake project sync demo
+assert_exit_code 1 "sync refuses to clobber (exit 1 from pancake, git fails)" run_pancake project sync demo
 assert_file_exists "user data preserved during sync" "$MOCK_HOME/pancake/demo/important.txt"
 cleanup_mock_home
 
@@ -130,6 +148,31 @@ write_valid_config
 assert_contains "open missing -> not found" "not found" run_pancake project open ghost
 cleanup_mock_home
 
+# import finds repositories, guesses commands and adds them to pancake.yml.
+write_valid_config
+mkdir -p "$MOCK_HOME/code"
+git clone -q "$MOCK_BARE_REPO" "$MOCK_HOME/code/svc" >/dev/null 2>&1
+git -C "$MOCK_HOME/code/svc" remote set-url origin git@example.com:org/svc.git
+echo "module svc" > "$MOCK_HOME/code/svc/go.mod"
+git clone -q "$MOCK_BARE_REPO" "$MOCK_HOME/code/known" >/dev/null 2>&1
+assert_contains "import skips repositories already configured" "already in pancake.yml" run_pancake project import "$MOCK_HOME/code" --yes
+assert_contains "import adds the new repository" "svc" run_pancake project list
+assert_contains "import guesses the build command" "go build ./..." cat "$MOCK_HOME/pancake.yml"
+cleanup_mock_home
+
+# add / rename / remove edit pancake.yml and the checkout.
+write_valid_config
+assert_exit_code 1 "add with an invalid port is rejected" run_pancake project add git@example.com:org/api.git --port 99999
+assert_exit_code 0 "add a project from its remote" run_pancake project add git@example.com:org/api.git --build "echo api"
+assert_contains "added project is listed" "api" run_pancake project list
+run_pancake project sync demo >/dev/null 2>&1
+assert_exit_code 0 "rename a project" run_pancake project rename demo renamed
+assert_file_exists "rename moves the checkout" "$MOCK_HOME/pancake/renamed/.git"
+assert_exit_code 0 "remove keeps the checkout without --delete" run_pancake project remove renamed
+assert_file_exists "checkout kept after remove" "$MOCK_HOME/pancake/renamed/.git"
+assert_exit_code 1 "remove of an unknown project fails" run_pancake project remove renamed
+cleanup_mock_home
+
 print_summary
 RESULT=$?
 rm -f /tmp/pancake_test_out
@@ -56,12 +56,18 @@ type ChatGPTClient struct {
 
 // NewChatGPTClient creates a new ChatGPT client from the provided configuration.
 func NewChatGPTClient(config ChatGPTConfig) (*ChatGPTClient, error) {
+	apiKey, err := ResolveAPIKey("chatgpt", config.APIKey, config.APIKeyEnv, config.APIKeyCmd)
+	if err != nil {
+		return nil, fmt.Errorf("❌ could not get the ChatGPT API key: %w", err)
+	}
+	config.APIKey = apiKey
 	if config.APIKey == "" {
 		return nil, fmt.Errorf("❌ ChatGPT API key is not set.\n" +
-			"Please run 'pancake edit config' to open the configuration file and add your API key.\n\n" +
+			"Store it with 'pancake secret set chatgpt', or run 'pancake edit config' and set\n" +
+			"'api_key_env' (an environment variable) or 'api_key_cmd' (a command that prints it).\n\n" +
 			"Example configuration in pancake.yml:\n" +
 			"chatgpt:\n" +
-			"  api_key: \"YOUR_API_KEY_HERE\"\n" +
+			"  api_key_env: OPENAI_API_KEY\n" +
 			"  model: \"gpt-3.5-turbo\"\n" +
 			"  url: \"https://api.openai.com/v1/chat/completions\"\n" +
 			"  temperature: 0.7")
@@ -39,7 +39,16 @@ const (
   pancake open [PROJECT_NAME]
   pancake build [PROJECT_NAME]
   pancake run [PROJECT_NAME]
+  pancake stop [PROJECT_NAME]
+  pancake restart [PROJECT_NAME]
   pancake edit config 
+  pancake config show --resolved
+  pancake config migrate --dry-run
+  pancake config validate
+  pancake config path
+  pancake config share
+  pancake secret set <name>
+  pancake profile use <profile_name>
 
 Troubleshooting:
   pancake edit config             or pancake p ec
@@ -56,6 +65,8 @@ const (
 )
 
 const DefaultYMLContent = `# Pancake Configuration File.
+version: 1 # Format version, upgraded by pancake config migrate
+
 # Home directory for project storage
 home: $HOME/pancake # For MacOS & Linux
 #home: '%userprofile%/pancake' # For Windows
@@ -65,14 +76,14 @@ code_editor: code . # Preferred code editor (code -> VS Code, idea -> IntelliJ I
 default_ai: gemini
 
 chatgpt:
-  api_key: ""
+  api_key: "" # or api_key_env: OPENAI_API_KEY, api_key_cmd: pass show openai, or 'pancake secret set chatgpt'
   temperature: 0.7
   url: "https://api.openai.com/v1/chat/completions"
   model: "gpt-3.5-turbo"
   context: "PRINT OUTPUT IN MARKDOWN. You are a helpful assistant that translates natural language into executable shell commands..."
 
 gemini:
-  api_key: ""
+  api_key: "" # or api_key_env: GEMINI_API_KEY, api_key_cmd: pass show gemini, or 'pancake secret set gemini'
   temperature: 0.7
   url: "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent"
   context: "You are a helpful assistant that translates natural language into executable shell commands. Only provide the command, with no extra text or explanation."
@@ -109,7 +120,18 @@ Further Assistance:
 	ProjectDescription = `Usage:
   pancake list                                     or  pancake [project|p] l
   pancake [sync|open|build|run|pwd] <project_name> or  pancake [project|p] [s|o|b|r|p] <project_name>
-  pancake monitor                                  or  pancake [project|p] m
+  pancake [sync|build] [--jobs N]                  or  pancake [project|p] [s|b] [-j N]
+  pancake sync <project_name> --autostash          or  pancake [project|p] s <project_name> --autostash
+  pancake [stop|restart] <project_name>            or  pancake [project|p] [stop|restart] <project_name>
+  pancake [sync|build|run|stop] [--group G|--tag T|<project_name>...]
+  pancake monitor [--watch]                        or  pancake [project|p] m [-w]
+  pancake logs [project_name...] [-f]              or  pancake [project|p] logs [project_name...] [-f]
+  pancake status [--fetch]                         or  pancake [project|p] status [--fetch]
+  pancake env <project_name>                       or  pancake [project|p] env <project_name>
+  pancake do <project_name> <task>                 or  pancake [project|p] do <project_name> <task>
+  pancake project import <dir> [--yes]             or  pancake p import <dir> [-y]
+  pancake project add <remote_url> [--name N]      or  pancake p add <remote_url> [--name N]
+  pancake project [remove|rename] <project_name>   or  pancake p [rm|mv] <project_name>
 
 Troubleshooting:
   pancake edit config             or pancake p ec
@@ -118,11 +140,14 @@ Troubleshooting:
 	ProjectErrorAddConfig  = `Run 'pancake edit config' to check if project exists in configuration file`
 	ProjectErrorSync       = `Run 'pancake sync <project_name>' to sync the project.`
 	ProjectErrorAddCommand = `Run 'pancake edit config' to add commands.`
+	ProjectErrorPortInUse  = `Stop the process using the port, change 'port' in pancake.yml, or run with --force to start anyway.`
 )
 
 const (
-	ConfigFileName = "pancake.yml"
-	ConfigIntro    = "Edit ~/pancake.yml (run 'pancake edit config')."
+	ConfigFileName      = "pancake.yml"
+	LocalConfigFileName = ".pancake.yml" // used when pancake runs in its directory or below
+	ConfigPathEnv       = "PANCAKE_CONFIG"
+	ConfigIntro         = "Edit ~/pancake.yml (run 'pancake edit config')."
 
 	ConfigErrNotFound = `pancake.yml was not found at %s.
 Run 'pancake init' to create it, or copy your backup pancake.yml to your home directory.`
@@ -149,19 +174,84 @@ Run 'pancake edit config'.`
 Allowed values: 'gemini' or 'chatgpt'. Remove the line to disable AI.
 Run 'pancake edit config'.`
 
-	ConfigErrProjectNameInvalid = `project name '%s' contains path separators and is unsafe.
+	ConfigErrProjectNameInvalid = `project name '%s' is unsafe: it is empty, starts with '.' or '-', or contains path separators.
 Rename it in pancake.yml under 'projects:' (use letters, numbers, '-', '_').
 Run 'pancake edit config'.`
 
 	ConfigErrProjectRemoteMissing = `project '%s' is missing 'remote_ssh_url' in pancake.yml.
 Add it under 'projects: %s: remote_ssh_url: git@github.com:org/repo.git'.
+Run 'pancake edit config'.`
+
+	ConfigWarnProjectPortInvalid = `project '%s' has a 'port' that is not a number between 1 and 65535: '%s'.
+'pancake run' skips its port check. Use e.g. port: "3000" to enable it.`
+
+	ConfigErrProjectDepthInvalid = `project '%s' has an invalid 'depth': %d.
+Use a positive number of commits for a shallow clone, or remove 'depth' for a full clone.
+Run 'pancake edit config'.`
+
+	ConfigErrProjectBranchInvalid = `project '%s' has an invalid 'branch': '%s'.
+Use the name of a branch on the remote, e.g. branch: main.
+Run 'pancake edit config'.`
+
+	ConfigErrProjectHealthInvalid = `project '%s' has an invalid 'health' section: %s.
+Example: health: { url: "http://localhost:3000/health", status: 200, timeout: 60s }
+Run 'pancake edit config'.`
+
+	ConfigErrProjectTaskInvalid = `project '%s' has an invalid entry under 'commands': %v.
+Steps starting with '@' run another task of the same project, e.g. test: [go vet ./..., "@unit"].
+Run 'pancake edit config'.`
+
+	ConfigErrProjectDependencyUnknown = `project '%s' depends on unknown project '%s'.
+Add that project under 'projects:' or remove it from 'depends_on'.
+Run 'pancake edit config'.`
+
+	ConfigErrProjectDependencyCycle = `projects depend on each other in a cycle: %s.
+Remove one of these entries from 'depends_on'.
+Run 'pancake edit config'.`
+
+	ConfigErrGroupMemberUnknown = `group '%s' lists unknown project '%s'.
+Add that project under 'projects:' or remove it from the group under 'groups:'.
+Run 'pancake edit config'.`
+
+	ConfigErrProfileUnknown = `profile '%s' is not defined under 'profiles:' in pancake.yml.
+Available profiles: %s. Switch with 'pancake profile use <name>'.`
+
+	ConfigErrProfileNameReserved = `profile name '%s' is reserved for the top-level settings of pancake.yml.
+Rename the profile under 'profiles:'.
+Run 'pancake edit config'.`
+
+	ConfigErrFieldType = `config field '%s' must be %s, not %s.
+Run 'pancake edit config'.`
+
+	ConfigWarnFieldUnknown = `unknown config field '%s'%s; pancake ignores it.`
+
+	ConfigWarnVersionOld = `pancake.yml is at format version %d; pancake upgrades it to version %d the next time it changes the file.
+Run 'pancake config migrate' to upgrade it now, or 'pancake config migrate --dry-run' to see the changes.`
+
+	ConfigWarnProjectPortDuplicate = `project '%s' uses the same 'port' as project '%s': %d.
+Only one of them can run at a time; give one of them a different port if they run together.`
+
+	ConfigErrVersionTooNew = `pancake.yml has 'version: %d', but this pancake only understands up to version %d.
+Upgrade pancake, or restore a backup written by an older version.`
+
+	ConfigErrMigrationFailed = `could not upgrade pancake.yml to the current format:
+%s
+Run 'pancake config migrate --dry-run' to see the changes, or fix pancake.yml with 'pancake edit config'.`
+
+	ConfigErrIncludedEntry = `%s '%s' comes from %s, which pancake.yml includes, so it cannot be removed or renamed in pancake.yml.
+Edit %s instead, or remove it from 'include:'.`
+
+	ConfigErrIncludeFailed = `could not merge the files listed under 'include:' in pancake.yml:
+%s
+Check each entry is a readable file or <git remote>//<path to file>.
 Run 'pancake edit config'.`
 
 	ConfigHomeDirNotExists = `pancake home directory '%s' does not exist.
 Run 'pancake init' to create it, or create it manually: mkdir -p '%s'.`
 
 	ConfigHintEditConfig = `Troubleshooting:
-  - 'pancake edit config'   opens ~/pancake.yml in your editor
+  - 'pancake edit config'   opens pancake.yml in your editor
+  - 'pancake config path'   shows which pancake.yml is used (--config, PANCAKE_CONFIG, .pancake.yml)
   - 'pancake init --force'  re-creates a fresh config (backs up the old one)
   - 'pancake version'       shows the installed version
   - Docs: https://github.com/a6h15hek/pancake/blob/main/USAGE.md`
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DependencyOrder returns names together with everything they depend on,
+// ordered so that every project comes after its dependencies. Projects
+// without an order between them keep a stable, alphabetical order.
+func DependencyOrder(projects map[string]Project, names ...string) ([]string, error) {
+	var order []string
+	state := make(map[string]int) // 1 = visiting, 2 = done
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %s", formatCycle(append(path, name), name))
+		case 2:
+			return nil
+		}
+		project, ok := projects[name]
+		if !ok {
+			if len(path) == 0 {
+				return fmt.Errorf("project %s not found", name)
+			}
+			return fmt.Errorf("project %s depends on unknown project %s", path[len(path)-1], name)
+		}
+		state[name] = 1
+		deps := append([]string(nil), project.DependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// DependencyCycle returns a cycle in the projects' depends_on lists, such as
+// [a b a], or nil if there is none. Unknown dependencies are ignored.
+func DependencyCycle(projects map[string]Project) []string {
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	state := make(map[string]int)
+	var cycle []string
+	var visit func(name string, path []string) bool
+	visit = func(name string, path []string) bool {
+		project, ok := projects[name]
+		if !ok || state[name] == 2 {
+			return false
+		}
+		if state[name] == 1 {
+			path = append(path, name)
+			for i, step := range path {
+				if step == name {
+					cycle = append([]string(nil), path[i:]...)
+					break
+				}
+			}
+			return true
+		}
+		state[name] = 1
+		for _, dep := range project.DependsOn {
+			if visit(dep, append(path, name)) {
+				return true
+			}
+		}
+		state[name] = 2
+		return false
+	}
+	for _, name := range names {
+		if visit(name, nil) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func formatCycle(path []string, start string) string {
+	for i, step := range path {
+		if step == start {
+			path = path[i:]
+			break
+		}
+	}
+	text := path[0]
+	for _, step := range path[1:] {
+		text += " -> " + step
+	}
+	return text
+}
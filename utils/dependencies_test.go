@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	projects := map[string]Project{
+		"web":  {DependsOn: []string{"api"}},
+		"api":  {DependsOn: []string{"db", "auth"}},
+		"auth": {DependsOn: []string{"db"}},
+		"db":   {},
+		"docs": {},
+	}
+	order, err := DependencyOrder(projects, "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"db", "auth", "api", "web"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	all, err := DependencyOrder(projects, "web", "docs", "db", "auth", "api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"db", "auth", "api", "docs", "web"}; !reflect.DeepEqual(all, want) {
+		t.Fatalf("order = %v, want %v", all, want)
+	}
+}
+
+func TestDependencyOrder_Cycle(t *testing.T) {
+	projects := map[string]Project{
+		"a": {DependsOn: []string{"b"}},
+		"b": {DependsOn: []string{"a"}},
+	}
+	if _, err := DependencyOrder(projects, "a"); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnifiedDiff returns a line diff of a and b in unified format with three
+// lines of context, or "" if they are equal.
+func UnifiedDiff(a, b, nameA, nameB string) string {
+	if a == b {
+		return ""
+	}
+	edits := lineEdits(splitLines(a), splitLines(b))
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		// Grow the hunk until more than 2*context unchanged lines follow.
+		first := max(start-context, 0)
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k
+			} else if k-end > 2*context {
+				break
+			}
+		}
+		last := min(end+context, len(edits)-1)
+
+		countA, countB := 0, 0
+		for _, e := range edits[first : last+1] {
+			if e.op != '+' {
+				countA++
+			}
+			if e.op != '-' {
+				countB++
+			}
+		}
+		startA, startB := edits[first].lineA+1, edits[first].lineB+1
+		if countA == 0 {
+			startA--
+		}
+		if countB == 0 {
+			startB--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+		for _, e := range edits[first : last+1] {
+			fmt.Fprintf(&out, "%c%s\n", e.op, e.line)
+		}
+		start = last + 1
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// lineEdit is a step of the edit script that turns one list of lines into
+// another: op is ' ' for a line both have, '-' for a line only the first
+// has and '+' for one only the second has. lineA and lineB are the indexes
+// in the two lists the step is at.
+type lineEdit struct {
+	op           byte
+	line         string
+	lineA, lineB int
+}
+
+// lineEdits returns the shortest edit script from linesA to linesB, with
+// deletions before insertions where both are possible.
+func lineEdits(linesA, linesB []string) []lineEdit {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// linesA[i:] and linesB[j:].
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []lineEdit
+	i, j := 0, 0
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			edits = append(edits, lineEdit{' ', linesA[i], i, j})
+			i++
+			j++
+		case i < len(linesA) && (j == len(linesB) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, lineEdit{'-', linesA[i], i, j})
+			i++
+		default:
+			edits = append(edits, lineEdit{'+', linesB[j], i, j})
+			j++
+		}
+	}
+	return edits
+}
@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	if diff := UnifiedDiff("a\nb\n", "a\nb\n", "old", "new"); diff != "" {
+		t.Errorf("expected no diff for equal input, got:\n%s", diff)
+	}
+
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	new := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+	want := `--- old
++++ new
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`
+	if diff := UnifiedDiff(old, new, "old", "new"); diff != want {
+		t.Errorf("got:\n%s\nwant:\n%s", diff, want)
+	}
+
+	want = "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+x\n"
+	if diff := UnifiedDiff("", "x\n", "old", "new"); diff != want {
+		t.Errorf("got:\n%s\nwant:\n%s", diff, want)
+	}
+}
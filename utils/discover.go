@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiscoveredRepository is a git checkout found by DiscoverRepositories,
+// with the project settings guessed from its files.
+type DiscoveredRepository struct {
+	Name    string
+	Path    string
+	Project Project
+}
+
+// skippedDiscoveryDirs are directories that never contain checkouts worth
+// importing and can be very large.
+var skippedDiscoveryDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"build":        true,
+	"dist":         true,
+}
+
+// DiscoverRepositories walks root and returns every git repository below it
+// that has an 'origin' remote, sorted by path. Repositories nested inside
+// another repository (such as submodules) are not returned separately, and
+// repositories without an origin are listed in skipped.
+func DiscoverRepositories(root string) (repos []DiscoveredRepository, skipped []string, err error) {
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if path == root {
+				return walkErr
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(entry.Name(), ".") || skippedDiscoveryDirs[entry.Name()]) {
+			return filepath.SkipDir
+		}
+		if !CheckExists(filepath.Join(path, ".git")) {
+			return nil
+		}
+		remote, err := gitOutput(path, "remote", "get-url", "origin")
+		if err != nil || remote == "" {
+			skipped = append(skipped, path)
+			return filepath.SkipDir
+		}
+		project := GuessProject(path)
+		project.RemoteSSHURL = remote
+		repos = append(repos, DiscoveredRepository{Name: filepath.Base(path), Path: path, Project: project})
+		return filepath.SkipDir
+	})
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Path < repos[j].Path })
+	return repos, skipped, err
+}
+
+// GuessProject fills in type, build and run for the checkout at dir from
+// the build files it contains. Fields stay empty when nothing is recognized.
+func GuessProject(dir string) Project {
+	has := func(name string) bool { return CheckExists(filepath.Join(dir, name)) }
+	switch {
+	case has("package.json"):
+		return guessNodeProject(dir, has)
+	case has("pom.xml"):
+		project := Project{Type: "java", Build: "mvn clean install", Run: "mvn exec:java"}
+		if fileContains(filepath.Join(dir, "pom.xml"), "spring-boot") {
+			project.Run = "mvn spring-boot:run"
+		}
+		return project
+	case has("build.gradle") || has("build.gradle.kts"):
+		gradle := "gradle"
+		if has("gradlew") {
+			gradle = "./gradlew"
+		}
+		project := Project{Type: "java", Build: gradle + " build", Run: gradle + " run"}
+		if fileContains(filepath.Join(dir, "build.gradle"), "spring-boot") || fileContains(filepath.Join(dir, "build.gradle.kts"), "spring-boot") {
+			project.Run = gradle + " bootRun"
+		}
+		return project
+	case has("go.mod"):
+		return Project{Type: "go", Build: "go build ./...", Run: "go run ."}
+	case has("Cargo.toml"):
+		return Project{Type: "rust", Build: "cargo build", Run: "cargo run"}
+	case has("pyproject.toml") || has("requirements.txt"):
+		project := Project{Type: "python"}
+		if has("requirements.txt") {
+			project.Build = "pip install -r requirements.txt"
+		} else {
+			project.Build = "pip install ."
+		}
+		if has("manage.py") {
+			project.Run = "python manage.py runserver"
+		}
+		return project
+	}
+	return Project{}
+}
+
+// guessNodeProject picks the package manager from the lock file, and the
+// type from well-known frontend frameworks in package.json.
+func guessNodeProject(dir string, has func(string) bool) Project {
+	manager := "npm"
+	switch {
+	case has("pnpm-lock.yaml"):
+		manager = "pnpm"
+	case has("yarn.lock"):
+		manager = "yarn"
+	}
+	project := Project{Type: "node", Build: manager + " install"}
+
+	var pkg struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		_ = json.Unmarshal(data, &pkg)
+	}
+	for _, framework := range []string{"react", "vue", "@angular/core", "next", "svelte", "vite"} {
+		_, dep := pkg.Dependencies[framework]
+		_, devDep := pkg.DevDependencies[framework]
+		if dep || devDep {
+			project.Type = "web"
+			break
+		}
+	}
+	switch {
+	case pkg.Scripts["start"] != "":
+		project.Run = manager + " start"
+	case pkg.Scripts["dev"] != "":
+		project.Run = manager + " run dev"
+	}
+	return project
+}
+
+func fileContains(path, text string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), text)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGuessProject(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "web", "package.json"), `{"scripts": {"dev": "vite"}, "dependencies": {"react": "^18"}}`)
+	writeTestFile(t, filepath.Join(dir, "web", "yarn.lock"), "")
+	writeTestFile(t, filepath.Join(dir, "api", "pom.xml"), "<artifactId>spring-boot-starter-web</artifactId>")
+	writeTestFile(t, filepath.Join(dir, "svc", "go.mod"), "module svc\n")
+	writeTestFile(t, filepath.Join(dir, "cli", "Cargo.toml"), "[package]\n")
+
+	cases := map[string]Project{
+		"web":   {Type: "web", Build: "yarn install", Run: "yarn run dev"},
+		"api":   {Type: "java", Build: "mvn clean install", Run: "mvn spring-boot:run"},
+		"svc":   {Type: "go", Build: "go build ./...", Run: "go run ."},
+		"cli":   {Type: "rust", Build: "cargo build", Run: "cargo run"},
+		"empty": {},
+	}
+	for name, want := range cases {
+		got := GuessProject(filepath.Join(dir, name))
+		if got.Type != want.Type || got.Build != want.Build || got.Run != want.Run {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestDiscoverRepositories(t *testing.T) {
+	remote := newTestRemote(t)
+	root := t.TempDir()
+	for _, command := range []string{
+		"git clone -q " + remote + " team/api",
+		"git init -q local-only",
+		"git init -q node_modules/pkg",
+	} {
+		if err := ExecuteCommand(command, root, false); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+	}
+	writeTestFile(t, filepath.Join(root, "team", "api", "go.mod"), "module api\n")
+	if err := exec.Command("git", "-C", filepath.Join(root, "team", "api"), "init", "-q", "nested").Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	repos, skipped, err := DiscoverRepositories(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("repos = %+v, want only team/api", repos)
+	}
+	repo := repos[0]
+	if repo.Name != "api" || repo.Path != filepath.Join(root, "team", "api") {
+		t.Errorf("repo = %+v", repo)
+	}
+	if repo.Project.RemoteSSHURL != remote || repo.Project.Type != "go" {
+		t.Errorf("project = %+v", repo.Project)
+	}
+	if len(skipped) != 1 || skipped[0] != filepath.Join(root, "local-only") {
+		t.Errorf("skipped = %v, want local-only", skipped)
+	}
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParseEnvFile reads KEY=VALUE lines from a .env file. Blank lines, comments
+// and a leading "export " are ignored. Values may be quoted; single-quoted
+// values are taken literally and are not interpolated.
+func ParseEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("env_file %s does not exist", path)
+		}
+		return nil, fmt.Errorf("could not read env_file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE, got '%s'", path, lineNumber, line)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = strings.ReplaceAll(value[1:len(value)-1], "$", "$$")
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.ReplaceAll(value[1:len(value)-1], `\n`, "\n")
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read env_file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// ResolveProjectEnv returns the variables pancake sets for a project's
+// commands, on top of its own environment. Later layers win: the global
+// env_file, the global env, the project's env_file and the project's env.
+// Values may reference other variables as ${VAR}; a reference resolves to the
+// same layer if the variable is defined there, otherwise to the layers below
+// (ending with pancake's own environment).
+func ResolveProjectEnv(config *Config, projectName string) (map[string]string, error) {
+	project, ok := config.Projects[projectName]
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", projectName)
+	}
+	projectPath := filepath.Join(config.Home, projectName)
+
+	var layers []map[string]string
+	for _, path := range config.EnvFile {
+		values, err := ParseEnvFile(resolveEnvFilePath(config.Home, path))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, values)
+	}
+	layers = append(layers, config.Env)
+	for _, path := range project.EnvFile {
+		values, err := ParseEnvFile(resolveEnvFilePath(projectPath, path))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, values)
+	}
+	layers = append(layers, project.Env)
+
+	base := environMap(os.Environ())
+	resolved := make(map[string]string)
+	for _, layer := range layers {
+		for key, value := range expandEnvLayer(layer, base) {
+			base[key] = value
+			resolved[key] = value
+		}
+	}
+	return resolved, nil
+}
+
+func resolveEnvFilePath(dir, path string) string {
+	expanded, err := ExpandHomePath(path)
+	if err != nil || expanded == "" {
+		expanded = path
+	}
+	if filepath.IsAbs(expanded) {
+		return expanded
+	}
+	return filepath.Join(dir, expanded)
+}
+
+// expandEnvLayer interpolates ${VAR} references in one layer of variables.
+func expandEnvLayer(layer, base map[string]string) map[string]string {
+	resolved := make(map[string]string, len(layer))
+	visiting := make(map[string]bool)
+	var resolve func(key string) string
+	resolve = func(key string) string {
+		if value, ok := resolved[key]; ok {
+			return value
+		}
+		visiting[key] = true
+		value := os.Expand(layer[key], func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			// A variable referring to itself (PATH: ${PATH}:...) or to a
+			// variable that refers back to it sees the value below this layer.
+			if _, inLayer := layer[name]; inLayer && !visiting[name] {
+				return resolve(name)
+			}
+			return base[name]
+		})
+		visiting[key] = false
+		resolved[key] = value
+		return value
+	}
+	for key := range layer {
+		resolve(key)
+	}
+	return resolved
+}
+
+func environMap(environ []string) map[string]string {
+	values := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if key, value, found := strings.Cut(entry, "="); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// MergeEnv returns environ with the variables in overrides set, in the
+// KEY=VALUE form expected by exec.Cmd.Env.
+func MergeEnv(environ []string, overrides map[string]string) []string {
+	merged := make([]string, 0, len(environ)+len(overrides))
+	for _, entry := range environ {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, overridden := overrides[key]; !overridden {
+			merged = append(merged, entry)
+		}
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		merged = append(merged, key+"="+overrides[key])
+	}
+	return merged
+}
+
+var secretEnvMarkers = []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "CREDENTIAL", "PRIVATE", "API_KEY", "ACCESS_KEY"}
+
+// IsSecretEnvKey guesses from its name whether a variable holds a secret.
+func IsSecretEnvKey(key string) bool {
+	upper := strings.ToUpper(key)
+	if upper == "KEY" || strings.HasSuffix(upper, "_KEY") {
+		return true
+	}
+	for _, marker := range secretEnvMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecret hides all but the first two characters of a secret value.
+func MaskSecret(value string) string {
+	if len(value) <= 4 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:2] + strings.Repeat("*", 8)
+}
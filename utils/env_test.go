@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# comment
+export HOST=localhost
+PORT=8080 # trailing comment
+GREETING="hello world"
+LITERAL='$HOST'
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := ParseEnvFile(path)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]string{"HOST": "localhost", "PORT": "8080", "GREETING": "hello world", "LITERAL": "$$HOST"}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %q, want %q", key, values[key], value)
+		}
+	}
+}
+
+func TestParseEnvFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("NOT A VARIABLE\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEnvFile(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Fatalf("expected an error with the line number, got %v", err)
+	}
+}
+
+func TestResolveProjectEnv_LayersAndInterpolation(t *testing.T) {
+	home := t.TempDir()
+	projectPath := filepath.Join(home, "api")
+	if err := os.MkdirAll(projectPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectPath, ".env"), []byte("DB_HOST=db.local\nLITERAL='${DB_HOST}'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PANCAKE_TEST_BASE", "/opt/bin")
+	config := &Config{
+		Home: home,
+		Env:  map[string]string{"STAGE": "dev", "DB_HOST": "global"},
+		Projects: map[string]Project{
+			"api": {
+				EnvFile: StringList{".env"},
+				Env: map[string]string{
+					"DB_URL":            "postgres://${DB_HOST}/app_${STAGE}",
+					"PANCAKE_TEST_BASE": "${PANCAKE_TEST_BASE}:/extra",
+				},
+			},
+		},
+	}
+	env, err := ResolveProjectEnv(config, "api")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	want := map[string]string{
+		"STAGE":             "dev",
+		"DB_HOST":           "db.local",
+		"DB_URL":            "postgres://db.local/app_dev",
+		"PANCAKE_TEST_BASE": "/opt/bin:/extra",
+		"LITERAL":           "${DB_HOST}",
+	}
+	for key, value := range want {
+		if env[key] != value {
+			t.Errorf("%s = %q, want %q", key, env[key], value)
+		}
+	}
+}
+
+func TestResolveProjectEnv_MissingEnvFile(t *testing.T) {
+	config := &Config{
+		Home:     t.TempDir(),
+		Projects: map[string]Project{"api": {EnvFile: StringList{"missing.env"}}},
+	}
+	if _, err := ResolveProjectEnv(config, "api"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing env_file error, got %v", err)
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	merged := MergeEnv([]string{"A=1", "B=2"}, map[string]string{"B": "3", "C": "4"})
+	if strings.Join(merged, ",") != "A=1,B=3,C=4" {
+		t.Fatalf("unexpected merge: %v", merged)
+	}
+}
+
+func TestIsSecretEnvKey(t *testing.T) {
+	for _, key := range []string{"API_KEY", "GITHUB_TOKEN", "DB_PASSWORD", "aws_secret_access_key", "STRIPE_KEY"} {
+		if !IsSecretEnvKey(key) {
+			t.Errorf("%s should be treated as a secret", key)
+		}
+	}
+	for _, key := range []string{"PATH", "PORT", "KEYBOARD", "NODE_ENV"} {
+		if IsSecretEnvKey(key) {
+			t.Errorf("%s should not be treated as a secret", key)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile takes an exclusive lock for path, waiting for other pancake
+// processes that hold it. The lock lives in a separate path+".lock" file so
+// that path itself can be replaced by WriteFileAtomic while it is held.
+func LockFile(path string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file for %s: %w", path, err)
+	}
+	if err := lockFileHandle(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not lock %s: %w", path, err)
+	}
+	return func() {
+		unlockFileHandle(file)
+		file.Close()
+	}, nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it over path, so readers see either the old or the new contents and never
+// a partly written file. If path is a symlink, the file it points to is
+// replaced and the link is kept. An existing file keeps its mode; perm is
+// the mode of a new one.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	path = resolveSymlink(path)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// resolveSymlink returns the file path points to, following symlinks, or
+// path itself if it is not a symlink. A link to a file that does not exist
+// yet resolves to that file.
+func resolveSymlink(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if target, err := os.Readlink(path); err == nil {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		return target
+	}
+	return path
+}
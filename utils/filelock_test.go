@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestModifyConfig_ConcurrentUpdatesMerge(t *testing.T) {
+	writeConfig(t, validConfig)
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := ModifyConfig(func(cfg *Config) error {
+				cfg.Tools = append(cfg.Tools, fmt.Sprintf("tool-%d", i))
+				return nil
+			}); err != nil {
+				t.Errorf("modify: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for i := 0; i < writers; i++ {
+		want := fmt.Sprintf("tool-%d", i)
+		found := false
+		for _, tool := range cfg.Tools {
+			found = found || tool == want
+		}
+		if !found {
+			t.Errorf("%s was lost; tools = %v", want, cfg.Tools)
+		}
+	}
+}
+
+func TestModifyConfig_InvalidChangeNotWritten(t *testing.T) {
+	configPath := writeConfig(t, validConfig)
+	err := ModifyConfig(func(cfg *Config) error {
+		cfg.Home = "relative/path"
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	data, _ := os.ReadFile(configPath)
+	if string(data) != validConfig {
+		t.Errorf("config was changed:\n%s", data)
+	}
+}
+
+func TestUpdateProjectProcesses_ConcurrentUpdatesMerge(t *testing.T) {
+	dir := t.TempDir()
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := UpdateProjectProcesses(dir, func(processes map[string]ProcessRecord) {
+				processes[fmt.Sprintf("project-%d", i)] = ProcessRecord{PID: 1000 + i}
+			}); err != nil {
+				t.Errorf("update: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	processes := make(map[string]ProcessRecord)
+	if err := LoadProjectProcesses(dir, &processes); err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != writers {
+		t.Errorf("got %d records, want %d: %v", len(processes), writers, processes)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.Name() != PIDsFileName && entry.Name() != PIDsFileName+".lock" {
+			t.Errorf("unexpected file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomic_FollowsSymlinkAndKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks and Unix modes need extra privileges on Windows")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "pancake.yml")
+	link := filepath.Join(dir, "pancake.yml")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("dotfiles", "pancake.yml"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(link, []byte("new\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("the symlink was replaced: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new\n" {
+		t.Errorf("target contains %q", data)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	fresh := filepath.Join(dir, "fresh.yml")
+	if err := WriteFileAtomic(fresh, []byte("x\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(fresh); info.Mode().Perm() != 0640 {
+		t.Errorf("new file mode = %v, want 0640", info.Mode().Perm())
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 )
@@ -32,9 +34,11 @@ func CheckExists(path string) bool {
 	return err == nil
 }
 
-func CloneRepository(path, remoteURL string) error {
+// CloneRepository clones remoteURL into path, or pulls if it is already a
+// checkout. Git output goes to output if given, otherwise to the terminal.
+func CloneRepository(path, remoteURL string, options GitOptions, output ...io.Writer) error {
 	if CheckExists(filepath.Join(path, ".git")) {
-		return PullChanges(path)
+		return PullChanges(path, options, output...)
 	}
 	if CheckExists(path) {
 		return fmt.Errorf("target path %s already exists and is not a git repository; move or remove it before syncing", path)
@@ -43,16 +47,71 @@ func CloneRepository(path, remoteURL string) error {
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
 		return fmt.Errorf("could not create parent directory %s: %w", parentDir, err)
 	}
-	if err := ExecuteCommand(fmt.Sprintf("git clone %s %s", remoteURL, path), ".", true); err != nil {
+	if err := options.checkBranch(); err != nil {
+		return err
+	}
+	args := append(append([]string{"clone"}, options.cloneFlags()...), "--", remoteURL, path)
+	if err := executeGitCommand(".", output, args...); err != nil {
 		return fmt.Errorf("git clone failed for %s: %w. Ensure your SSH key is set up (ssh -T git@github.com) or switch remote_ssh_url to an https URL in pancake.yml", remoteURL, err)
 	}
 	return nil
 }
 
-func PullChanges(path string) error {
-	if err := ExecuteCommand("git pull", path, true); err != nil {
+// PullChanges updates the checkout at path: it switches to options.Branch if
+// set, pulls, and updates submodules. It refuses to touch a working tree with
+// uncommitted changes unless options.AutoStash is set, in which case they are
+// stashed first and restored afterwards.
+func PullChanges(path string, options GitOptions, output ...io.Writer) error {
+	if err := options.checkBranch(); err != nil {
+		return err
+	}
+	dirty, err := WorkingTreeDirty(path)
+	if err != nil {
+		return fmt.Errorf("could not check the working tree of %s: %w", path, err)
+	}
+	if dirty && !options.AutoStash {
+		return fmt.Errorf("%s has uncommitted changes; commit or stash them first, or sync with --autostash", path)
+	}
+	if dirty {
+		if err := executeGitCommand(path, output, "stash", "push", "-m", "pancake autostash"); err != nil {
+			return fmt.Errorf("git stash failed in %s: %w", path, err)
+		}
+	}
+	pullErr := pullBranch(path, options, output)
+	if dirty {
+		if err := executeGitCommand(path, output, "stash", "pop"); err != nil {
+			return fmt.Errorf("your changes in %s are kept in 'git stash list' because 'git stash pop' failed: %w", path, err)
+		}
+	}
+	return pullErr
+}
+
+func pullBranch(path string, options GitOptions, output []io.Writer) error {
+	pull := []string{"pull"}
+	if options.Branch != "" {
+		current, err := gitOutput(path, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return fmt.Errorf("could not read the current branch in %s: %w", path, err)
+		}
+		if current != options.Branch {
+			fetch := append(append([]string{"fetch"}, options.depthFlags()...), "origin", "+refs/heads/"+options.Branch+":refs/remotes/origin/"+options.Branch)
+			if err := executeGitCommand(path, output, fetch...); err != nil {
+				return fmt.Errorf("could not fetch branch %s in %s: %w", options.Branch, path, err)
+			}
+			if err := executeGitCommand(path, output, "checkout", options.Branch); err != nil {
+				return fmt.Errorf("could not check out branch %s in %s: %w", options.Branch, path, err)
+			}
+		}
+		pull = []string{"pull", "origin", options.Branch}
+	}
+	if err := executeGitCommand(path, output, pull...); err != nil {
 		return fmt.Errorf("git pull failed in %s: %w", path, err)
 	}
+	if options.Submodules {
+		if err := executeGitCommand(path, output, append([]string{"submodule", "update", "--init", "--recursive"}, options.depthFlags()...)...); err != nil {
+			return fmt.Errorf("could not update submodules in %s: %w", path, err)
+		}
+	}
 	return nil
 }
 
@@ -95,25 +154,108 @@ func ExecuteCommand(cmdStr, dir string, isLogging ...bool) error {
 	return command.Run()
 }
 
-func ExecuteCommandInNewTerminal(cmdStr, dir, projectName string, projectPIDs *map[string]int) error {
+// ExecuteCommandWithOutput runs cmdStr in dir, echoing the command and its
+// stdout/stderr to out. A nil out discards everything, and a nil env
+// inherits pancake's environment.
+func ExecuteCommandWithOutput(cmdStr, dir string, env []string, out io.Writer) error {
+	command := buildShellCommand(cmdStr)
+	command.Dir = dir
+	command.Env = env
+	if out != nil {
+		fmt.Fprintf(out, "%s > %s\n", dir, cmdStr)
+		command.Stdout = out
+		command.Stderr = out
+	}
+	return command.Run()
+}
+
+// executeGitCommand runs git with args in dir, without a shell, echoing the
+// command and its output on the terminal, or into output[0] when the caller
+// buffers it.
+func executeGitCommand(dir string, output []io.Writer, args ...string) error {
+	out := io.Writer(os.Stdout)
+	if len(output) > 0 && output[0] != nil {
+		out = output[0]
+	}
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	fmt.Fprintf(out, "%s > git %s\n", dir, strings.Join(args, " "))
+	command.Stdout = out
+	command.Stderr = out
+	if len(output) == 0 || output[0] == nil {
+		command.Stderr = os.Stderr
+	}
+	return command.Run()
+}
+
+func ExecuteCommandInNewTerminal(cmdStr, dir, projectName string, env []string) (int, error) {
 	var command *exec.Cmd
 	switch runtime.GOOS {
 	case "windows":
 		command = exec.Command("cmd", "/c", "start", "cmd", "/k", fmt.Sprintf("cd /d %s && %s", dir, cmdStr))
 	case "darwin":
-		command = exec.Command("osascript", "-e", fmt.Sprintf(`tell application "Terminal" to do script "cd %s && %s"`, dir, cmdStr))
+		setup, err := terminalEnvSetup(env)
+		if err != nil {
+			return 0, fmt.Errorf("could not pass the environment of %s to the terminal: %w", projectName, err)
+		}
+		command = exec.Command("osascript", "-e", fmt.Sprintf(`tell application "Terminal" to do script "%scd %s && %s"`, setup, dir, cmdStr))
 	default:
 		terminal, ok := detectLinuxTerminal()
 		if !ok {
-			return fmt.Errorf("no supported terminal emulator found (tried gnome-terminal, konsole, xfce4-terminal, x-terminal-emulator, xterm). Open %s and run '%s' manually", dir, cmdStr)
+			return 0, fmt.Errorf("no supported terminal emulator found (tried gnome-terminal, konsole, xfce4-terminal, x-terminal-emulator, xterm). Open %s and run '%s' manually", dir, cmdStr)
 		}
-		command = exec.Command(terminal, "--", "sh", "-c", fmt.Sprintf("cd %s && %s; exec sh", dir, cmdStr))
+		setup, err := terminalEnvSetup(env)
+		if err != nil {
+			return 0, fmt.Errorf("could not pass the environment of %s to the terminal: %w", projectName, err)
+		}
+		command = exec.Command(terminal, "--", "sh", "-c", fmt.Sprintf("%scd %s && %s; exec sh", setup, dir, cmdStr))
 	}
+	command.Env = env
 	if err := command.Start(); err != nil {
-		return fmt.Errorf("could not launch terminal for %s: %w", projectName, err)
+		return 0, fmt.Errorf("could not launch terminal for %s: %w", projectName, err)
 	}
-	(*projectPIDs)[projectName] = command.Process.Pid
-	return nil
+	return command.Process.Pid, nil
+}
+
+var shellVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// terminalEnvSetup returns the shell commands that set the variables of env
+// that differ from pancake's own environment. Terminals that hand the window
+// to an already running server (macOS Terminal, gnome-terminal) do not pass
+// exec.Cmd.Env on, so the variables are written to a private file that the
+// shell in the new window sources and deletes. Keeping them out of the
+// command line keeps secrets out of ps and the terminal's scrollback.
+func terminalEnvSetup(env []string) (string, error) {
+	var script strings.Builder
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		if current, set := os.LookupEnv(key); (set && current == value) || !shellVariableName.MatchString(key) {
+			continue
+		}
+		fmt.Fprintf(&script, "export %s=%s\n", key, shellQuote(value))
+	}
+	if script.Len() == 0 {
+		return "", nil
+	}
+	file, err := os.CreateTemp("", "pancake-env-*.sh")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(script.String()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	path := shellQuote(file.Name())
+	return fmt.Sprintf(". %s; rm -f %s; ", path, path), nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
 }
 
 func detectLinuxTerminal() (string, bool) {
@@ -150,26 +292,64 @@ func PrintTable(data [][]string) {
 	fmt.Println("|")
 }
 
-func SaveProjectPIDs(fileLocation string, projectPIDs map[string]int) error {
-	data, err := json.Marshal(projectPIDs)
+const PIDsFileName = "pids.json"
+
+// ProcessRecord is what pancake remembers about a project it started, so that
+// later invocations can tell whether the PID still belongs to that project.
+type ProcessRecord struct {
+	PID       int    `json:"pid"`
+	StartTime uint64 `json:"start_time,omitempty"`
+	Cmdline   string `json:"cmdline,omitempty"`
+}
+
+// UnmarshalJSON also accepts the bare PIDs written by older pancake versions.
+func (r *ProcessRecord) UnmarshalJSON(data []byte) error {
+	var pid int
+	if err := json.Unmarshal(data, &pid); err == nil {
+		*r = ProcessRecord{PID: pid}
+		return nil
+	}
+	type plainRecord ProcessRecord
+	return json.Unmarshal(data, (*plainRecord)(r))
+}
+
+// UpdateProjectProcesses applies update to the records in pids.json while
+// holding its lock and returns the records that were written. The file is
+// read under the lock, so projects started or stopped by other pancake
+// processes in the meantime are not lost.
+func UpdateProjectProcesses(fileLocation string, update func(processes map[string]ProcessRecord)) (map[string]ProcessRecord, error) {
+	path := filepath.Join(fileLocation, PIDsFileName)
+	unlock, err := LockFile(path)
 	if err != nil {
-		return fmt.Errorf("could not encode project pids: %w", err)
+		return nil, err
+	}
+	defer unlock()
+
+	processes := make(map[string]ProcessRecord)
+	if err := LoadProjectProcesses(fileLocation, &processes); err != nil {
+		return nil, err
 	}
-	if err := os.MkdirAll(fileLocation, 0755); err != nil {
-		return fmt.Errorf("could not create %s: %w", fileLocation, err)
+	update(processes)
+	return processes, writeProjectProcesses(path, processes)
+}
+
+func writeProjectProcesses(path string, processes map[string]ProcessRecord) error {
+	data, err := json.Marshal(processes)
+	if err != nil {
+		return fmt.Errorf("could not encode project pids: %w", err)
 	}
-	return os.WriteFile(filepath.Join(fileLocation, "pids.json"), data, 0644)
+	return WriteFileAtomic(path, data, 0644)
 }
 
-func LoadProjectPIDs(fileLocation string, projectPIDs *map[string]int) error {
-	data, err := os.ReadFile(filepath.Join(fileLocation, "pids.json"))
+func LoadProjectProcesses(fileLocation string, processes *map[string]ProcessRecord) error {
+	data, err := os.ReadFile(filepath.Join(fileLocation, PIDsFileName))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil
 		}
 		return fmt.Errorf("could not read project pids file: %w", err)
 	}
-	return json.Unmarshal(data, projectPIDs)
+	return json.Unmarshal(data, processes)
 }
 
 func SetupChocolatey() error {
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -46,7 +48,7 @@ func TestCloneRepository_AlreadyGit_Pulls(t *testing.T) {
 	if err := os.MkdirAll(filepath.Join(repoPath, ".git"), 0755); err != nil {
 		t.Fatal(err)
 	}
-	err := CloneRepository(repoPath, "https://example.com/repo.git")
+	err := CloneRepository(repoPath, "https://example.com/repo.git", GitOptions{})
 	if err == nil {
 		t.Skip("git pull attempted (no remote configured) — skipping; safety guard did not trigger data loss")
 	}
@@ -62,7 +64,7 @@ func TestCloneRepository_NonGitDirExists_Refuses(t *testing.T) {
 	if err := os.WriteFile(important, []byte("do not delete me"), 0644); err != nil {
 		t.Fatal(err)
 	}
-	err := CloneRepository(repoPath, "https://example.com/repo.git")
+	err := CloneRepository(repoPath, "https://example.com/repo.git", GitOptions{})
 	if err == nil {
 		t.Fatal("expected error when non-git dir exists")
 	}
@@ -71,25 +73,27 @@ func TestCloneRepository_NonGitDirExists_Refuses(t *testing.T) {
 	}
 }
 
-func TestSaveAndLoadProjectPIDs_RoundTrip(t *testing.T) {
+func TestUpdateAndLoadProjectProcesses_RoundTrip(t *testing.T) {
 	dir := t.TempDir()
-	pids := map[string]int{"demo": 1234, "web": 5678}
-	if err := SaveProjectPIDs(dir, pids); err != nil {
+	if _, err := UpdateProjectProcesses(dir, func(processes map[string]ProcessRecord) {
+		processes["demo"] = ProcessRecord{PID: 1234}
+		processes["web"] = ProcessRecord{PID: 5678}
+	}); err != nil {
 		t.Fatalf("save: %v", err)
 	}
-	var loaded map[string]int
-	if err := LoadProjectPIDs(dir, &loaded); err != nil {
+	var loaded map[string]ProcessRecord
+	if err := LoadProjectProcesses(dir, &loaded); err != nil {
 		t.Fatalf("load: %v", err)
 	}
-	if loaded["demo"] != 1234 || loaded["web"] != 5678 {
+	if loaded["demo"].PID != 1234 || loaded["web"].PID != 5678 {
 		t.Fatalf("round-trip mismatch: %v", loaded)
 	}
 }
 
-func TestLoadProjectPIDs_NoFile(t *testing.T) {
+func TestLoadProjectProcesses_NoFile(t *testing.T) {
 	dir := t.TempDir()
-	var loaded map[string]int
-	if err := LoadProjectPIDs(dir, &loaded); err != nil {
+	var loaded map[string]ProcessRecord
+	if err := LoadProjectProcesses(dir, &loaded); err != nil {
 		t.Fatalf("missing file should not error, got: %v", err)
 	}
 	if len(loaded) != 0 {
@@ -97,6 +101,27 @@ func TestLoadProjectPIDs_NoFile(t *testing.T) {
 	}
 }
 
+func TestTerminalEnvSetup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("terminal windows inherit the environment on Windows")
+	}
+	env := MergeEnv(os.Environ(), map[string]string{"PANCAKE_TEST_VALUE": "it's $HOME"})
+	setup, err := terminalEnvSetup(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("sh", "-c", setup+`printf '%s' "$PANCAKE_TEST_VALUE"`).Output()
+	if err != nil {
+		t.Fatalf("sourcing %q failed: %v", setup, err)
+	}
+	if string(out) != "it's $HOME" {
+		t.Errorf("PANCAKE_TEST_VALUE = %q in the terminal", out)
+	}
+	if setup, _ := terminalEnvSetup(os.Environ()); setup != "" {
+		t.Errorf("expected nothing to set for pancake's own environment, got %q", setup)
+	}
+}
+
 func TestGetPackageManager(t *testing.T) {
 	pm := GetPackageManager()
 	if pm == "" {
@@ -81,12 +81,18 @@ type GeminiClient struct {
 
 // NewGeminiClient creates a new Gemini AI client from the provided configuration.
 func NewGeminiClient(config GeminiConfig) (*GeminiClient, error) {
+	apiKey, err := ResolveAPIKey("gemini", config.APIKey, config.APIKeyEnv, config.APIKeyCmd)
+	if err != nil {
+		return nil, fmt.Errorf("❌ could not get the Gemini API key: %w", err)
+	}
+	config.APIKey = apiKey
 	if config.APIKey == "" {
 		return nil, fmt.Errorf("❌ Gemini API key is not set.\n" +
-			"Please run 'pancake edit config' to open the configuration file and add your API key.\n\n" +
+			"Store it with 'pancake secret set gemini', or run 'pancake edit config' and set\n" +
+			"'api_key_env' (an environment variable) or 'api_key_cmd' (a command that prints it).\n\n" +
 			"Example configuration in pancake.yml:\n" +
 			"gemini:\n" +
-			"  api_key: \"YOUR_API_KEY_HERE\"\n" +
+			"  api_key_env: GEMINI_API_KEY\n" +
 			"  url: \"https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent\"\n" +
 			"  temperature: 0.7\n" +
 			"  context: \"You are a helpful assistant that translates natural language into executable shell commands...\"")
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitOptions controls how sync clones and updates a project's checkout.
+type GitOptions struct {
+	Branch     string
+	Depth      int
+	Submodules bool
+	AutoStash  bool
+}
+
+// ProjectGitOptions returns the git settings of a project.
+func ProjectGitOptions(project Project) GitOptions {
+	return GitOptions{Branch: project.Branch, Depth: project.Depth, Submodules: project.Submodules}
+}
+
+func (o GitOptions) cloneFlags() []string {
+	var flags []string
+	if o.Branch != "" {
+		flags = append(flags, "--branch", o.Branch)
+	}
+	flags = append(flags, o.depthFlags()...)
+	if o.Submodules {
+		flags = append(flags, "--recurse-submodules")
+		if o.Depth > 0 {
+			flags = append(flags, "--shallow-submodules")
+		}
+	}
+	return flags
+}
+
+func (o GitOptions) depthFlags() []string {
+	if o.Depth > 0 {
+		return []string{"--depth", strconv.Itoa(o.Depth)}
+	}
+	return nil
+}
+
+// checkBranch asks git whether o.Branch is a valid branch name, so a bad
+// 'branch' setting is reported before any git command uses it.
+func (o GitOptions) checkBranch() error {
+	if o.Branch == "" {
+		return nil
+	}
+	if _, err := gitOutput(".", "check-ref-format", "--branch", o.Branch); err != nil {
+		return fmt.Errorf("'%s' is not a valid branch name", o.Branch)
+	}
+	return nil
+}
+
+// gitOutput runs git in dir and returns its trimmed stdout.
+func gitOutput(dir string, args ...string) (string, error) {
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	out, err := command.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// WorkingTreeDirty reports whether the checkout at path has uncommitted
+// changes to tracked files.
+func WorkingTreeDirty(path string) (bool, error) {
+	status, err := gitOutput(path, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return status != "", nil
+}
+
+// GitStatus summarises the state of a checkout for 'pancake status'.
+type GitStatus struct {
+	Branch      string // empty for a detached HEAD
+	Dirty       bool   // tracked files have uncommitted changes
+	Untracked   bool
+	HasUpstream bool
+	Ahead       int
+	Behind      int
+	LastCommit  time.Time
+}
+
+// ReadGitStatus inspects the checkout at path.
+func ReadGitStatus(path string) (GitStatus, error) {
+	var status GitStatus
+	branch, err := gitOutput(path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return status, err
+	}
+	if branch != "HEAD" {
+		status.Branch = branch
+	}
+
+	porcelain, err := gitOutput(path, "status", "--porcelain")
+	if err != nil {
+		return status, err
+	}
+	for _, line := range strings.Split(porcelain, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "??"):
+			status.Untracked = true
+		default:
+			status.Dirty = true
+		}
+	}
+
+	if counts, err := gitOutput(path, "rev-list", "--left-right", "--count", "HEAD...@{upstream}"); err == nil {
+		if _, err := fmt.Sscanf(counts, "%d\t%d", &status.Ahead, &status.Behind); err == nil {
+			status.HasUpstream = true
+		}
+	}
+
+	if committed, err := gitOutput(path, "log", "-1", "--format=%ct"); err == nil && committed != "" {
+		if seconds, err := strconv.ParseInt(committed, 10, 64); err == nil {
+			status.LastCommit = time.Unix(seconds, 0)
+		}
+	}
+	return status, nil
+}
+
+// FetchRemotes updates the remote-tracking branches of the checkout at path.
+func FetchRemotes(path string) error {
+	if _, err := gitOutput(path, "fetch", "--quiet", "--prune"); err != nil {
+		return fmt.Errorf("git fetch failed in %s: %w", path, err)
+	}
+	return nil
+}
+
+// HeadCommit returns the commit checked out at path.
+func HeadCommit(path string) (string, error) {
+	return gitOutput(path, "rev-parse", "HEAD")
+}
+
+// CommitsBetween lists the commits reachable from to but not from, newest
+// first, as "<short hash> <subject>" lines.
+func CommitsBetween(path, from, to string) ([]string, error) {
+	log, err := gitOutput(path, "log", "--format=%h %s", from+".."+to)
+	if err != nil || log == "" {
+		return nil, err
+	}
+	return strings.Split(log, "\n"), nil
+}
+
+// ShortCommit abbreviates a commit hash for display.
+func ShortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRemote creates a bare repository with a "main" and a "feature"
+// branch and returns its file:// URL.
+func newTestRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "remote.git")
+	seed := filepath.Join(dir, "seed")
+	for _, command := range []string{
+		"git init -q --bare " + bare,
+		"git init -q -b main " + seed,
+	} {
+		if err := ExecuteCommand(command, dir, false); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+	}
+	for _, command := range []string{
+		"echo one > file.txt",
+		"git add file.txt",
+		"git commit -q -m one",
+		"echo two >> file.txt",
+		"git commit -q -am two",
+		"git checkout -q -b feature",
+		"echo feature > feature.txt",
+		"git add feature.txt",
+		"git commit -q -m feature",
+		"git push -q " + bare + " main feature",
+	} {
+		if err := ExecuteCommand(command, seed, false); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+	}
+	return "file://" + bare
+}
+
+func TestCloneRepository_BranchAndDepth(t *testing.T) {
+	remote := newTestRemote(t)
+	path := filepath.Join(t.TempDir(), "project")
+	if err := CloneRepository(path, remote, GitOptions{Branch: "feature", Depth: 1}, io.Discard); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if branch, _ := gitOutput(path, "rev-parse", "--abbrev-ref", "HEAD"); branch != "feature" {
+		t.Fatalf("branch = %s, want feature", branch)
+	}
+	if shallow, _ := gitOutput(path, "rev-parse", "--is-shallow-repository"); shallow != "true" {
+		t.Fatalf("expected a shallow clone, got %s", shallow)
+	}
+}
+
+func TestPullChanges_SwitchesBranch(t *testing.T) {
+	remote := newTestRemote(t)
+	path := filepath.Join(t.TempDir(), "project")
+	if err := CloneRepository(path, remote, GitOptions{Branch: "main"}, io.Discard); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if err := PullChanges(path, GitOptions{Branch: "feature"}, io.Discard); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if branch, _ := gitOutput(path, "rev-parse", "--abbrev-ref", "HEAD"); branch != "feature" {
+		t.Fatalf("branch = %s, want feature", branch)
+	}
+}
+
+func TestCloneRepository_BranchIsNotRunByAShell(t *testing.T) {
+	remote := newTestRemote(t)
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	err := CloneRepository(filepath.Join(dir, "project"), remote, GitOptions{Branch: "main;touch " + marker}, io.Discard)
+	if err == nil {
+		t.Fatal("expected the clone to fail for a branch that does not exist")
+	}
+	if CheckExists(marker) {
+		t.Fatal("the branch name was run as a shell command")
+	}
+}
+
+func TestGitOptions_CheckBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, branch := range []string{"main", "feature/x", "release-1.2"} {
+		if err := (GitOptions{Branch: branch}).checkBranch(); err != nil {
+			t.Errorf("checkBranch(%q) = %v", branch, err)
+		}
+		if !validBranchName(branch) {
+			t.Errorf("validBranchName(%q) = false", branch)
+		}
+	}
+	for _, branch := range []string{"-x", "a..b", "a b", "x.lock", "a/.b", "a~1", "@{u}"} {
+		if err := (GitOptions{Branch: branch}).checkBranch(); err == nil {
+			t.Errorf("checkBranch(%q) accepted an invalid name", branch)
+		}
+		if validBranchName(branch) {
+			t.Errorf("validBranchName(%q) = true", branch)
+		}
+	}
+}
+
+func TestPullChanges_DirtyTree(t *testing.T) {
+	remote := newTestRemote(t)
+	path := filepath.Join(t.TempDir(), "project")
+	if err := CloneRepository(path, remote, GitOptions{Branch: "main"}, io.Discard); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	file := filepath.Join(path, "file.txt")
+	if err := os.WriteFile(file, []byte("local edit\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := PullChanges(path, GitOptions{Branch: "main"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Fatalf("expected pull to refuse a dirty tree, got %v", err)
+	}
+
+	if err := PullChanges(path, GitOptions{Branch: "main", AutoStash: true}, io.Discard); err != nil {
+		t.Fatalf("pull with autostash: %v", err)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "local edit\n" {
+		t.Fatalf("local changes were not restored, file contains %q", data)
+	}
+}
+
+func TestReadGitStatus(t *testing.T) {
+	remote := newTestRemote(t)
+	path := filepath.Join(t.TempDir(), "project")
+	if err := CloneRepository(path, remote, GitOptions{Branch: "main"}, io.Discard); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	for _, command := range []string{"git reset -q --hard HEAD~1", "echo local > local.txt", "git add local.txt", "git commit -q -m local", "echo edit >> file.txt"} {
+		if err := ExecuteCommand(command, path, false); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+	}
+	status, err := ReadGitStatus(path)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.Branch != "main" || !status.Dirty || !status.HasUpstream {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if status.Ahead != 1 || status.Behind != 1 {
+		t.Fatalf("ahead/behind = %d/%d, want 1/1", status.Ahead, status.Behind)
+	}
+	if status.LastCommit.IsZero() {
+		t.Fatal("expected the last commit time")
+	}
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHealthTimeout = 60 * time.Second
+	healthProbeTimeout   = 3 * time.Second
+	healthPollInterval   = 500 * time.Millisecond
+
+	HealthHealthy   = "Healthy"
+	HealthUnhealthy = "Unhealthy"
+)
+
+// HealthCheck describes how to tell that a running project is ready. Every
+// probe that is set (url, tcp, command) must pass.
+type HealthCheck struct {
+	URL     string `yaml:"url,omitempty"`
+	Status  int    `yaml:"status,omitempty"` // expected HTTP status; any 2xx or 3xx if unset
+	TCP     string `yaml:"tcp,omitempty"`    // port or host:port that must accept connections
+	Command string `yaml:"command,omitempty"`
+	Timeout string `yaml:"timeout,omitempty"` // how long run waits, e.g. "90s"; 60s if unset
+}
+
+// TimeoutDuration returns how long to wait for the project to become healthy.
+func (h *HealthCheck) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultHealthTimeout
+}
+
+// validate returns a description of what is wrong with the health section.
+func (h *HealthCheck) validate() string {
+	if h.URL == "" && h.TCP == "" && h.Command == "" {
+		return "set at least one of 'url', 'tcp' or 'command'"
+	}
+	if h.URL != "" && !strings.HasPrefix(h.URL, "http://") && !strings.HasPrefix(h.URL, "https://") {
+		return fmt.Sprintf("'url' must start with http:// or https://, got '%s'", h.URL)
+	}
+	if h.Status != 0 && (h.Status < 100 || h.Status > 599) {
+		return fmt.Sprintf("'status' must be an HTTP status code, got %d", h.Status)
+	}
+	if h.TCP != "" {
+		if _, err := ParsePort(healthTCPPort(h.TCP)); err != nil {
+			return fmt.Sprintf("'tcp' must be a port or host:port, got '%s'", h.TCP)
+		}
+	}
+	if h.Timeout != "" {
+		if d, err := time.ParseDuration(h.Timeout); err != nil || d <= 0 {
+			return fmt.Sprintf("'timeout' must be a duration like 30s or 2m, got '%s'", h.Timeout)
+		}
+	}
+	return ""
+}
+
+func healthTCPPort(target string) string {
+	if _, port, err := net.SplitHostPort(target); err == nil {
+		return port
+	}
+	return target
+}
+
+func healthTCPAddress(target string) string {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort("127.0.0.1", target)
+}
+
+// CheckHealth probes a project once and returns why it is not healthy, or nil.
+// Commands run in dir with env, the project's environment; a nil env
+// inherits pancake's.
+func CheckHealth(h HealthCheck, dir string, env []string) error {
+	if h.TCP != "" {
+		conn, err := net.DialTimeout("tcp", healthTCPAddress(h.TCP), healthProbeTimeout)
+		if err != nil {
+			return fmt.Errorf("nothing accepts connections on %s", healthTCPAddress(h.TCP))
+		}
+		conn.Close()
+	}
+	if h.URL != "" {
+		client := http.Client{Timeout: healthProbeTimeout}
+		resp, err := client.Get(h.URL)
+		if err != nil {
+			return fmt.Errorf("GET %s failed: %w", h.URL, err)
+		}
+		resp.Body.Close()
+		if h.Status != 0 && resp.StatusCode != h.Status {
+			return fmt.Errorf("GET %s returned %d, expected %d", h.URL, resp.StatusCode, h.Status)
+		}
+		if h.Status == 0 && resp.StatusCode >= 400 {
+			return fmt.Errorf("GET %s returned %d", h.URL, resp.StatusCode)
+		}
+	}
+	if h.Command != "" {
+		if err := runHealthCommand(h.Command, dir, env); err != nil {
+			return fmt.Errorf("'%s' failed: %w", h.Command, err)
+		}
+	}
+	return nil
+}
+
+// runHealthCommand runs a health command in a process group of its own, so
+// that a command that hangs is killed together with everything it started.
+func runHealthCommand(cmdStr, dir string, env []string) error {
+	command := buildShellCommand(cmdStr)
+	command.Dir = dir
+	command.Env = env
+	newProcessGroup(command)
+	if err := command.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- command.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(healthProbeTimeout):
+		if err := terminateProcessTree(command.Process.Pid, true); err != nil {
+			command.Process.Kill()
+		}
+		<-done
+		return fmt.Errorf("timed out after %s", healthProbeTimeout)
+	}
+}
+
+// errNotRunning is returned by WaitForHealthy when the project exits while
+// it is being waited on.
+var errNotRunning = errors.New("the project exited before it became healthy")
+
+// WaitForHealthy probes a project until it is healthy, the timeout passes or
+// alive (if not nil) reports that its process has exited.
+func WaitForHealthy(h HealthCheck, dir string, env []string, alive func() bool) error {
+	timeout := h.TimeoutDuration()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := CheckHealth(h, dir, env)
+		if err == nil {
+			return nil
+		}
+		if alive != nil && !alive() {
+			return errNotRunning
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not healthy after %s: %w", timeout, err)
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
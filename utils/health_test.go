@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHealth_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := CheckHealth(HealthCheck{URL: server.URL + "/health", Status: 200}, "", nil); err != nil {
+		t.Fatalf("expected healthy, got %v", err)
+	}
+	if err := CheckHealth(HealthCheck{URL: server.URL + "/other"}, "", nil); err == nil {
+		t.Fatal("expected a 503 to be unhealthy")
+	}
+	if err := CheckHealth(HealthCheck{URL: server.URL + "/health", Status: 204}, "", nil); err == nil {
+		t.Fatal("expected a status mismatch to be unhealthy")
+	}
+}
+
+func TestCheckHealth_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	if err := CheckHealth(HealthCheck{TCP: addr}, "", nil); err != nil {
+		t.Fatalf("expected healthy, got %v", err)
+	}
+	listener.Close()
+	if err := CheckHealth(HealthCheck{TCP: addr}, "", nil); err == nil {
+		t.Fatal("expected a closed port to be unhealthy")
+	}
+}
+
+func TestCheckHealth_Command(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckHealth(HealthCheck{Command: "exit 0"}, dir, nil); err != nil {
+		t.Fatalf("expected healthy, got %v", err)
+	}
+	if err := CheckHealth(HealthCheck{Command: "exit 1"}, dir, nil); err == nil {
+		t.Fatal("expected a failing command to be unhealthy")
+	}
+}
+
+func TestCheckHealth_CommandUsesProjectEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	env := MergeEnv(os.Environ(), map[string]string{"HEALTH_READY": "yes"})
+	if err := CheckHealth(HealthCheck{Command: `test "$HEALTH_READY" = yes`}, t.TempDir(), env); err != nil {
+		t.Fatalf("expected the command to see the project's env, got %v", err)
+	}
+}
+
+func TestCheckHealth_CommandTimeoutKillsChildren(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	dir := t.TempDir()
+	err := CheckHealth(HealthCheck{Command: "sleep 30 & echo $! > child.pid; wait"}, dir, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "child.pid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	if !waitForExit(pid, 2*time.Second) {
+		t.Errorf("the command's child %d is still running", pid)
+	}
+}
+
+func TestWaitForHealthy_TimesOut(t *testing.T) {
+	start := time.Now()
+	err := WaitForHealthy(HealthCheck{Command: "exit 1", Timeout: "1s"}, t.TempDir(), nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "not healthy after 1s") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("waited too long: %s", time.Since(start))
+	}
+}
+
+func TestWaitForHealthy_ProcessExited(t *testing.T) {
+	err := WaitForHealthy(HealthCheck{Command: "exit 1"}, t.TempDir(), nil, func() bool { return false })
+	if err != errNotRunning {
+		t.Fatalf("expected errNotRunning, got %v", err)
+	}
+}
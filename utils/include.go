@@ -0,0 +1,369 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// IncludeSource is one entry of the 'include:' list: a local file, or a file
+// in a git repository written as <remote>//<path>[?ref=<branch>].
+type IncludeSource struct {
+	Entry  string
+	Path   string // local file, or the file inside the cached clone
+	Remote string
+	Ref    string
+}
+
+// ParseIncludeSource interprets an include entry. Relative local paths are
+// resolved against baseDir, the directory of the file that includes them.
+func ParseIncludeSource(entry, baseDir string) (IncludeSource, error) {
+	source := IncludeSource{Entry: entry}
+	remote, ref, _ := strings.Cut(entry, "?ref=")
+	isRemote := strings.Contains(remote, "://") || strings.HasPrefix(remote, "git@")
+	if i := strings.LastIndex(remote, "//"); i > 0 && remote[i-1] != ':' {
+		source.Remote, source.Path = remote[:i], remote[i+2:]
+	} else if isRemote {
+		source.Remote, source.Path = remote, ConfigFileName
+	}
+	if source.Remote != "" {
+		source.Ref = ref
+		if source.Path == "" || filepath.IsAbs(source.Path) || strings.HasPrefix(filepath.Clean(source.Path), "..") {
+			return source, fmt.Errorf("include '%s' must name a file inside the repository, e.g. %s//pancake.yml", entry, source.Remote)
+		}
+		return source, nil
+	}
+
+	path, err := ExpandHomePath(entry)
+	if err != nil {
+		return source, err
+	}
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	source.Path = path
+	return source, nil
+}
+
+// includeCacheDir is where git-hosted includes are cloned.
+func includeCacheDir(source IncludeSource) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(source.Remote + "?ref=" + source.Ref))
+	name := ProjectNameFromRemote(source.Remote) + "-" + hex.EncodeToString(sum[:])[:12]
+	return filepath.Join(cacheDir, "pancake", "includes", name), nil
+}
+
+// read returns the contents of the included file. Git-hosted files are read
+// from a shallow clone in the user cache, made on first use and updated only
+// when refresh is set.
+func (s IncludeSource) read(refresh bool) ([]byte, error) {
+	if s.Remote == "" {
+		return os.ReadFile(s.Path)
+	}
+	dir, err := includeCacheDir(s)
+	if err != nil {
+		return nil, err
+	}
+	if !CheckExists(filepath.Join(dir, ".git")) {
+		args := []string{"clone", "-q", "--depth", "1"}
+		if s.Ref != "" {
+			args = append(args, "--branch", s.Ref)
+		}
+		if err := runGit("", append(args, s.Remote, dir)...); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	} else if refresh {
+		ref := s.Ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if err := runGit(dir, "fetch", "-q", "--depth", "1", "origin", ref); err != nil {
+			return nil, err
+		}
+		if err := runGit(dir, "reset", "-q", "--hard", "FETCH_HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	return os.ReadFile(filepath.Join(dir, s.Path))
+}
+
+func runGit(dir string, args ...string) error {
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	if out, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ResolvedConfig is pancake.yml merged with the files it includes.
+type ResolvedConfig struct {
+	Root    *yamlv3.Node
+	origins map[*yamlv3.Node]string
+}
+
+// Origin returns "file:line" for a node of Root.
+func (r *ResolvedConfig) Origin(node *yamlv3.Node) string {
+	return fmt.Sprintf("%s:%d", r.origins[node], node.Line)
+}
+
+// File returns the file a node of Root was read from: the config file path
+// or the include entry naming it.
+func (r *ResolvedConfig) File(node *yamlv3.Node) string {
+	return r.origins[node]
+}
+
+type includeResolver struct {
+	refresh  bool
+	origins  map[*yamlv3.Node]string
+	visiting map[string]bool
+}
+
+// ResolveConfigIncludes merges the files listed under 'include:' into the
+// config file data read from configPath. Includes are merged in order and
+// may include other files; mappings are merged key by key, and any other
+// value from a later file, or from data itself, replaces the earlier one.
+// With refresh, git-hosted includes are fetched again.
+func ResolveConfigIncludes(configPath string, data []byte, refresh bool) (*ResolvedConfig, error) {
+	resolver := &includeResolver{refresh: refresh, origins: make(map[*yamlv3.Node]string), visiting: make(map[string]bool)}
+	root, err := resolver.load(configPath, configPath, filepath.Dir(configPath), data, true)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedConfig{Root: root, origins: resolver.origins}, nil
+}
+
+func (r *includeResolver) load(key, origin, baseDir string, data []byte, top bool) (*yamlv3.Node, error) {
+	if r.visiting[key] {
+		return nil, fmt.Errorf("'%s' includes itself", origin)
+	}
+	r.visiting[key] = true
+	defer delete(r.visiting, key)
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", origin, err)
+	}
+	root := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	if len(doc.Content) > 0 && !(doc.Content[0].Kind == yamlv3.ScalarNode && doc.Content[0].Tag == "!!null") {
+		root = doc.Content[0]
+	}
+	if root.Kind != yamlv3.MappingNode {
+		return nil, fmt.Errorf("%s: the top level must be a mapping of settings", origin)
+	}
+	r.annotate(root, origin)
+
+	var entries StringList
+	if index := yamlMappingIndex(root, "include"); index >= 0 {
+		if err := root.Content[index+1].Decode(&entries); err != nil {
+			return nil, fmt.Errorf("%s: 'include' must be a path or a list of paths", origin)
+		}
+		// Only the top-level file's own include list is part of the result.
+		if !top {
+			root.Content = append(root.Content[:index], root.Content[index+2:]...)
+		}
+	}
+
+	merged := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	for _, entry := range entries {
+		source, err := ParseIncludeSource(entry, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		included, err := source.read(r.refresh)
+		if err != nil {
+			return nil, fmt.Errorf("could not read include '%s': %w", entry, err)
+		}
+		includeKey, includeBase := source.Path, filepath.Dir(source.Path)
+		if source.Remote != "" {
+			includeKey = source.Remote + "//" + source.Path
+			// Relative includes inside a repository stay local to the clone.
+			if dir, err := includeCacheDir(source); err == nil {
+				includeBase = filepath.Dir(filepath.Join(dir, source.Path))
+			}
+		}
+		node, err := r.load(includeKey, entry, includeBase, included, false)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeConfigNodes(merged, node)
+	}
+	return mergeConfigNodes(merged, root), nil
+}
+
+func (r *includeResolver) annotate(node *yamlv3.Node, origin string) {
+	r.origins[node] = origin
+	for _, child := range node.Content {
+		r.annotate(child, origin)
+	}
+}
+
+// mergeConfigNodes merges overlay into base. Mappings are merged key by key,
+// keeping the key order of overlay followed by the keys only base has;
+// anything else in overlay replaces base.
+func mergeConfigNodes(base, overlay *yamlv3.Node) *yamlv3.Node {
+	if base.Kind != yamlv3.MappingNode || overlay.Kind != yamlv3.MappingNode {
+		return overlay
+	}
+	merged := *overlay
+	merged.Content = nil
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if baseValue := yamlMappingValue(base, key.Value); baseValue != nil {
+			value = mergeConfigNodes(baseValue, value)
+		}
+		merged.Content = append(merged.Content, key, value)
+	}
+	for i := 0; i+1 < len(base.Content); i += 2 {
+		if yamlMappingIndex(overlay, base.Content[i].Value) < 0 {
+			merged.Content = append(merged.Content, base.Content[i], base.Content[i+1])
+		}
+	}
+	return &merged
+}
+
+// Encode returns the merged configuration as YAML. With origins, every value
+// is followed by a comment naming the file and line it came from.
+func (r *ResolvedConfig) Encode(origins bool) ([]byte, error) {
+	if origins {
+		stripComments(r.Root)
+		r.commentOrigins(r.Root)
+	}
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(r.Root); err != nil {
+		return nil, fmt.Errorf("could not encode the resolved configuration: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *ResolvedConfig) commentOrigins(node *yamlv3.Node) {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := node.Content[i+1]
+			if value.Kind == yamlv3.MappingNode {
+				r.commentOrigins(value)
+				continue
+			}
+			if value.Kind == yamlv3.SequenceNode {
+				value.Style = 0
+				for _, item := range value.Content {
+					r.commentOrigins(item)
+				}
+				if len(value.Content) == 0 {
+					value.LineComment = "from " + r.Origin(value)
+				}
+				continue
+			}
+			value.LineComment = "from " + r.Origin(value)
+		}
+	case yamlv3.ScalarNode:
+		node.LineComment = "from " + r.Origin(node)
+	case yamlv3.SequenceNode:
+		for _, item := range node.Content {
+			r.commentOrigins(item)
+		}
+	}
+}
+
+func stripComments(node *yamlv3.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, child := range node.Content {
+		stripComments(child)
+	}
+}
+
+// checkIncludedRemovals makes sure that the projects, groups, profiles and
+// tools removed from config are gone once data is merged with its includes
+// again. Writing pancake.yml cannot remove what an included file supplies,
+// so that is reported, naming the include, rather than silently kept.
+func checkIncludedRemovals(configPath string, data []byte, onDisk, config *Config) error {
+	if len(config.Include) == 0 {
+		return nil
+	}
+	merged, err := decodeConfig(configPath, data)
+	if err != nil {
+		return err
+	}
+	kind, name := "", ""
+	for _, section := range []struct {
+		kind                   string
+		before, after, written []string
+	}{
+		{"projects", sortedKeys(onDisk.Projects), sortedKeys(config.Projects), sortedKeys(merged.Projects)},
+		{"groups", sortedKeys(onDisk.Groups), sortedKeys(config.Groups), sortedKeys(merged.Groups)},
+		{"profiles", sortedKeys(onDisk.Profiles), sortedKeys(config.Profiles), sortedKeys(merged.Profiles)},
+		{"tools", onDisk.Tools, config.Tools, merged.Tools},
+	} {
+		for _, entry := range section.before {
+			if !slices.Contains(section.after, entry) && slices.Contains(section.written, entry) {
+				kind, name = section.kind, entry
+				break
+			}
+		}
+		if kind != "" {
+			break
+		}
+	}
+	if kind == "" {
+		return nil
+	}
+
+	resolved, err := ResolveConfigIncludes(configPath, data, false)
+	if err != nil {
+		return errors.New(fmt.Sprintf(ConfigErrIncludeFailed, err.Error()))
+	}
+	var node *yamlv3.Node
+	if kind == "profiles" {
+		node = lookupConfigNode(resolved.Root, []string{kind, name})
+	} else {
+		path := config.settingPath([]string{kind})
+		node = lookupConfigNode(resolved.Root, append(path, name))
+		if kind == "tools" {
+			node = lookupConfigNode(resolved.Root, path)
+			if tools := yamlValueAt(resolved.Root, path); tools != nil {
+				for _, item := range tools.Content {
+					if item.Value == name {
+						node = item
+					}
+				}
+			}
+		}
+	}
+	return errors.New(fmt.Sprintf(ConfigErrIncludedEntry, strings.TrimSuffix(kind, "s"), name, resolved.File(node), resolved.File(node)))
+}
+
+// yamlValueAt returns the value at the path of mapping keys below root, or
+// nil if it is not there.
+func yamlValueAt(root *yamlv3.Node, path []string) *yamlv3.Node {
+	node := root
+	for _, key := range path {
+		if node = yamlMappingValue(node, key); node == nil {
+			return nil
+		}
+	}
+	return node
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIncludeSource(t *testing.T) {
+	base := filepath.Join(string(filepath.Separator), "work")
+	cases := []struct {
+		entry             string
+		path, remote, ref string
+	}{
+		{"team.yml", filepath.Join(base, "team.yml"), "", ""},
+		{"git@github.com:org/config.git//team/pancake.yml?ref=main", "team/pancake.yml", "git@github.com:org/config.git", "main"},
+		{"https://github.com/org/config.git", ConfigFileName, "https://github.com/org/config.git", ""},
+		{"https://github.com/org/config.git//base.yml", "base.yml", "https://github.com/org/config.git", ""},
+	}
+	for _, c := range cases {
+		source, err := ParseIncludeSource(c.entry, base)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.entry, err)
+		}
+		if source.Path != c.path || source.Remote != c.remote || source.Ref != c.ref {
+			t.Errorf("%s: got %+v", c.entry, source)
+		}
+	}
+	if _, err := ParseIncludeSource("git@github.com:org/config.git//../escape.yml", base); err == nil {
+		t.Error("expected an error for a path outside the repository")
+	}
+}
+
+func TestResolveConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "team", "base.yml"), `include: common.yml
+code_editor: idea .
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    build: make
+`)
+	writeTestFile(t, filepath.Join(dir, "team", "common.yml"), "tools: [git]\ndefault_ai: gemini\n")
+	configPath := filepath.Join(dir, ConfigFileName)
+	local := `include: [team/base.yml]
+code_editor: code .
+projects:
+  api:
+    build: go build ./...
+`
+	resolved, err := ResolveConfigIncludes(configPath, []byte(local), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := resolved.Encode(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"code_editor: code . # from " + configPath + ":2",
+		"remote_ssh_url: git@github.com:org/api.git # from team/base.yml:5",
+		"build: go build ./... # from " + configPath + ":5",
+		"- git # from common.yml:1",
+		"default_ai: gemini # from common.yml:2",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("resolved config is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(string(out), "include:") != 1 {
+		t.Errorf("nested include lists should not be merged:\n%s", out)
+	}
+}
+
+func TestResolveConfigIncludes_Cycle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.yml"), "include: b.yml\n")
+	writeTestFile(t, filepath.Join(dir, "b.yml"), "include: a.yml\n")
+	_, err := ResolveConfigIncludes(filepath.Join(dir, ConfigFileName), []byte("include: a.yml\n"), false)
+	if err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+}
+
+func TestResolveConfigIncludes_Git(t *testing.T) {
+	remote := newTestRemote(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	// Publish a shared config file on the remote's main branch.
+	work := t.TempDir()
+	for _, command := range []string{
+		"git clone -q " + remote + " .",
+		"git checkout -q main",
+		"echo 'tools: [jq]' > team.yml",
+		"git add team.yml",
+		"git commit -q -m team",
+		"git push -q origin main",
+	} {
+		if err := ExecuteCommand(command, work, false); err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+	}
+	data := []byte("include: " + remote + "//team.yml?ref=main\n")
+	resolved, err := ResolveConfigIncludes(filepath.Join(t.TempDir(), ConfigFileName), data, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tools := yamlMappingValue(resolved.Root, "tools"); tools == nil || tools.Content[0].Value != "jq" {
+		t.Fatalf("git include was not merged: %+v", tools)
+	}
+}
+
+func TestGetConfig_Include(t *testing.T) {
+	configPath := writeConfig(t, "include: team.yml\n"+validConfig)
+	writeTestFile(t, filepath.Join(filepath.Dir(configPath), "team.yml"), `projects:
+  shared:
+    remote_ssh_url: git@github.com:org/shared.git
+`)
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, ok := cfg.Projects["shared"]; !ok {
+		t.Fatalf("included project missing: %v", cfg.Projects)
+	}
+
+	if err := ModifyConfig(func(cfg *Config) error {
+		cfg.Tools = append(cfg.Tools, "jq")
+		return nil
+	}); err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if strings.Contains(string(data), "shared") {
+		t.Errorf("included settings were copied into pancake.yml:\n%s", data)
+	}
+}
+
+func TestModifyConfig_RemoveIncludedProject(t *testing.T) {
+	configPath := writeConfig(t, "include: team.yml\n"+validConfig)
+	writeTestFile(t, filepath.Join(filepath.Dir(configPath), "team.yml"), `projects:
+  shared:
+    remote_ssh_url: git@github.com:org/shared.git
+`)
+	before, _ := os.ReadFile(configPath)
+
+	for name, modify := range map[string]func(*Config) error{
+		"remove": func(cfg *Config) error {
+			_, err := RemoveProject(cfg, "shared")
+			return err
+		},
+		"rename": func(cfg *Config) error { return RenameProject(cfg, "shared", "common") },
+	} {
+		err := ModifyConfig(modify)
+		if err == nil || !strings.Contains(err.Error(), "team.yml") {
+			t.Errorf("%s: expected an error naming team.yml, got %v", name, err)
+		}
+		if after, _ := os.ReadFile(configPath); string(after) != string(before) {
+			t.Errorf("%s: pancake.yml was changed:\n%s", name, after)
+		}
+	}
+
+	if err := ModifyConfig(func(cfg *Config) error {
+		_, err := RemoveProject(cfg, "demo")
+		return err
+	}); err != nil {
+		t.Fatalf("removing a local project: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LogMaxBytes   = 10 * 1024 * 1024
+	LogMaxBackups = 3
+	logTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+func ProjectLogPath(home, projectName string) string {
+	return filepath.Join(home, LogsDirName, projectName+".log")
+}
+
+// RotatingLog appends timestamped lines to a log file and rotates it to
+// <path>.1 ... <path>.N once it grows past maxBytes.
+type RotatingLog struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	file     *os.File
+	size     int64
+}
+
+func OpenRotatingLog(path string, maxBytes int64, backups int) (*RotatingLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create log directory %s: %w", filepath.Dir(path), err)
+	}
+	l := &RotatingLog{path: path, maxBytes: maxBytes, backups: backups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *RotatingLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open log file %s: %w", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not stat log file %s: %w", l.path, err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// WriteLine writes text prefixed with the current time.
+func (l *RotatingLog) WriteLine(text string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	line := time.Now().Format(logTimeLayout) + " " + text + "\n"
+	if l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.WriteString(line)
+	l.size += int64(n)
+	return err
+}
+
+func (l *RotatingLog) rotate() error {
+	l.file.Close()
+	for i := l.backups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if l.backups > 0 {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("could not rotate log file %s: %w", l.path, err)
+		}
+	} else if err := os.Truncate(l.path, 0); err != nil {
+		return fmt.Errorf("could not truncate log file %s: %w", l.path, err)
+	}
+	return l.open()
+}
+
+func (l *RotatingLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// LogEntry is one line of a project's log.
+type LogEntry struct {
+	Time    time.Time
+	Project string
+	Text    string
+}
+
+// parseLogLine splits the timestamp written by RotatingLog from the text.
+// Lines without a timestamp are returned with a zero time.
+func parseLogLine(line string) (time.Time, string) {
+	if space := strings.IndexByte(line, ' '); space > 0 {
+		if t, err := time.Parse(logTimeLayout, line[:space]); err == nil {
+			return t, line[space+1:]
+		}
+	}
+	return time.Time{}, line
+}
+
+// LogFiles returns the existing files of a rotated log, oldest first.
+func LogFiles(path string) []string {
+	var files []string
+	for i := LogMaxBackups; i >= 1; i-- {
+		if rotated := fmt.Sprintf("%s.%d", path, i); CheckExists(rotated) {
+			files = append(files, rotated)
+		}
+	}
+	if CheckExists(path) {
+		files = append(files, path)
+	}
+	return files
+}
+
+// ReadLogEntries reads every line of a project's log, including rotated
+// files, oldest first. Lines without a timestamp inherit the previous one.
+func ReadLogEntries(path, project string) ([]LogEntry, error) {
+	var entries []LogEntry
+	var last time.Time
+	for _, file := range LogFiles(path) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			entry := newLogEntry(project, scanner.Text(), &last)
+			entries = append(entries, entry)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+	}
+	return entries, nil
+}
+
+func newLogEntry(project, line string, last *time.Time) LogEntry {
+	t, text := parseLogLine(line)
+	if t.IsZero() {
+		t = *last
+	} else {
+		*last = t
+	}
+	return LogEntry{Time: t, Project: project, Text: text}
+}
+
+// MergeLogEntries interleaves the entries of several projects by time. The
+// sort is stable so lines with equal timestamps keep their file order.
+func MergeLogEntries(groups ...[]LogEntry) []LogEntry {
+	var merged []LogEntry
+	for _, group := range groups {
+		merged = append(merged, group...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Time.Before(merged[j].Time) })
+	return merged
+}
+
+// LogFollower returns lines appended to a log file since the last Poll and
+// keeps following it across rotations.
+type LogFollower struct {
+	path    string
+	project string
+	info    os.FileInfo
+	offset  int64
+	partial string
+	last    time.Time
+}
+
+// NewLogFollower starts following path from its current end.
+func NewLogFollower(path, project string) *LogFollower {
+	f := &LogFollower{path: path, project: project}
+	if info, err := os.Stat(path); err == nil {
+		f.info = info
+		f.offset = info.Size()
+	}
+	return f
+}
+
+func (f *LogFollower) Poll() ([]LogEntry, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []LogEntry
+	if f.info != nil && !os.SameFile(f.info, info) {
+		// The file was rotated: finish the old one (now <path>.1) first.
+		if old, err := f.readFrom(f.path+".1", f.offset); err == nil {
+			entries = append(entries, old...)
+		}
+		if f.partial != "" {
+			entries = append(entries, newLogEntry(f.project, f.partial, &f.last))
+		}
+		f.offset = 0
+		f.partial = ""
+	} else if info.Size() < f.offset {
+		f.offset = 0
+		f.partial = ""
+	}
+	f.info = info
+	current, err := f.readFrom(f.path, f.offset)
+	if err != nil {
+		return entries, err
+	}
+	return append(entries, current...), nil
+}
+
+func (f *LogFollower) readFrom(path string, offset int64) ([]LogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	f.offset = offset + int64(len(data))
+
+	text := f.partial + string(data)
+	lines := strings.Split(text, "\n")
+	// The last element is an incomplete line (or empty); keep it for the next poll.
+	f.partial = lines[len(lines)-1]
+	var entries []LogEntry
+	for _, line := range lines[:len(lines)-1] {
+		entries = append(entries, newLogEntry(f.project, line, &f.last))
+	}
+	return entries, nil
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingLog_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.log")
+	log, err := OpenRotatingLog(path, 100, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := log.WriteLine(strings.Repeat("x", 20)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	log.Close()
+	files := LogFiles(path)
+	if len(files) != 3 {
+		t.Fatalf("expected the log and 2 backups, got %v", files)
+	}
+	if files[len(files)-1] != path {
+		t.Fatalf("current log should be last, got %v", files)
+	}
+	if !CheckExists(path+".2") || CheckExists(path+".3") {
+		t.Fatalf("expected exactly two backups, got %v", files)
+	}
+}
+
+func TestReadLogEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.log")
+	content := "2024-05-01T10:00:00.000Z first\ncontinued\n2024-05-01T10:00:01.000Z second\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadLogEntries(path, "demo")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Text != "first" || entries[0].Project != "demo" {
+		t.Fatalf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Text != "continued" || !entries[1].Time.Equal(entries[0].Time) {
+		t.Fatalf("untimestamped line should inherit the previous time: %+v", entries[1])
+	}
+}
+
+func TestMergeLogEntries(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	api := []LogEntry{{Time: base, Project: "api", Text: "a1"}, {Time: base.Add(2 * time.Second), Project: "api", Text: "a2"}}
+	web := []LogEntry{{Time: base.Add(time.Second), Project: "web", Text: "w1"}}
+	merged := MergeLogEntries(api, web)
+	var got []string
+	for _, entry := range merged {
+		got = append(got, entry.Text)
+	}
+	if strings.Join(got, ",") != "a1,w1,a2" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+}
+
+func TestLogFollower_FollowsAcrossRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.log")
+	log, err := OpenRotatingLog(path, 120, 1)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer log.Close()
+	log.WriteLine("before")
+	follower := NewLogFollower(path, "demo")
+
+	log.WriteLine("one")
+	entries, err := follower.Poll()
+	if err != nil || len(entries) != 1 || entries[0].Text != "one" {
+		t.Fatalf("expected only the new line, got %+v (%v)", entries, err)
+	}
+
+	log.WriteLine("two")
+	log.WriteLine(strings.Repeat("y", 40))
+	log.WriteLine("after rotation")
+	entries, err = follower.Poll()
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Text)
+	}
+	if len(got) != 3 || got[0] != "two" || got[2] != "after rotation" {
+		t.Fatalf("expected lines from before and after rotation, got %v", got)
+	}
+}
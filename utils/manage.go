@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ProjectNameFromRemote derives a project name from the last path segment of
+// a git remote URL, e.g. "api" for git@github.com:org/api.git.
+func ProjectNameFromRemote(remoteURL string) string {
+	name := strings.TrimRight(remoteURL, "/")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(path.Clean(name), ".git")
+}
+
+// ProjectDir returns the checkout directory of a project under home. It
+// fails unless the directory is strictly inside home, so a bad name can
+// never make pancake move or delete home or anything outside it.
+func ProjectDir(home, projectName string) (string, error) {
+	projectPath := filepath.Join(home, projectName)
+	relative, err := filepath.Rel(home, projectPath)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("project '%s' would not be a directory inside %s", projectName, home)
+	}
+	return projectPath, nil
+}
+
+// AddProject adds a new project to config. It fails if the name is taken.
+func AddProject(config *Config, projectName string, project Project) error {
+	if _, exists := config.Projects[projectName]; exists {
+		return fmt.Errorf("project '%s' already exists in pancake.yml", projectName)
+	}
+	if project.Port != "" {
+		if _, err := ParsePort(project.Port); err != nil {
+			return fmt.Errorf("project '%s' cannot be added: %w", projectName, err)
+		}
+	}
+	if config.Projects == nil {
+		config.Projects = make(map[string]Project)
+	}
+	config.Projects[projectName] = project
+	return nil
+}
+
+// RemoveProject removes a project from config and from every group. Projects
+// that still depend on it are returned so the caller can refuse the change.
+func RemoveProject(config *Config, projectName string) (dependents []string, err error) {
+	if _, exists := config.Projects[projectName]; !exists {
+		return nil, fmt.Errorf("project '%s' not found in pancake.yml", projectName)
+	}
+	delete(config.Projects, projectName)
+	for group, members := range config.Groups {
+		config.Groups[group] = slices.DeleteFunc(members, func(member string) bool { return member == projectName })
+	}
+	for name, project := range config.Projects {
+		if slices.Contains(project.DependsOn, projectName) {
+			dependents = append(dependents, name)
+		}
+	}
+	slices.Sort(dependents)
+	return dependents, nil
+}
+
+// RenameProject renames a project in config, updating the groups and
+// depends_on entries that refer to it.
+func RenameProject(config *Config, oldName, newName string) error {
+	project, exists := config.Projects[oldName]
+	if !exists {
+		return fmt.Errorf("project '%s' not found in pancake.yml", oldName)
+	}
+	if _, taken := config.Projects[newName]; taken {
+		return fmt.Errorf("project '%s' already exists in pancake.yml", newName)
+	}
+	delete(config.Projects, oldName)
+	config.Projects[newName] = project
+	rename := func(names []string) {
+		for i, name := range names {
+			if name == oldName {
+				names[i] = newName
+			}
+		}
+	}
+	for _, members := range config.Groups {
+		rename(members)
+	}
+	for _, project := range config.Projects {
+		rename(project.DependsOn)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProjectNameFromRemote(t *testing.T) {
+	cases := map[string]string{
+		"git@github.com:org/api.git":      "api",
+		"https://github.com/org/web.git/": "web",
+		"https://github.com/org/cli":      "cli",
+		"/srv/git/repo.git":               "repo",
+		"git@host:repo.git":               "repo",
+	}
+	for remote, want := range cases {
+		if got := ProjectNameFromRemote(remote); got != want {
+			t.Errorf("ProjectNameFromRemote(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func manageConfig() *Config {
+	return &Config{
+		Groups: map[string][]string{"backend": {"api", "worker"}},
+		Projects: map[string]Project{
+			"api":    {RemoteSSHURL: "git@github.com:org/api.git"},
+			"worker": {RemoteSSHURL: "git@github.com:org/worker.git", DependsOn: []string{"api"}},
+		},
+	}
+}
+
+func TestAddProject(t *testing.T) {
+	config := &Config{}
+	if err := AddProject(config, "api", Project{RemoteSSHURL: "git@github.com:org/api.git"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := AddProject(config, "api", Project{}); err == nil {
+		t.Fatal("expected an error when the name is taken")
+	}
+	if err := AddProject(config, "web", Project{Port: "99999"}); err == nil {
+		t.Fatal("expected an error for a port out of range")
+	}
+}
+
+func TestRemoveProject(t *testing.T) {
+	config := manageConfig()
+	dependents, err := RemoveProject(config, "api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(dependents, []string{"worker"}) {
+		t.Errorf("dependents = %v, want [worker]", dependents)
+	}
+	if !reflect.DeepEqual(config.Groups["backend"], []string{"worker"}) {
+		t.Errorf("backend group = %v, want [worker]", config.Groups["backend"])
+	}
+	if _, err := RemoveProject(config, "ghost"); err == nil {
+		t.Fatal("expected an error for an unknown project")
+	}
+}
+
+func TestRenameProject(t *testing.T) {
+	config := manageConfig()
+	if err := RenameProject(config, "api", "core"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Projects["core"]; !ok {
+		t.Fatal("renamed project is missing")
+	}
+	if !reflect.DeepEqual(config.Projects["worker"].DependsOn, []string{"core"}) {
+		t.Errorf("depends_on = %v, want [core]", config.Projects["worker"].DependsOn)
+	}
+	if !reflect.DeepEqual(config.Groups["backend"], []string{"core", "worker"}) {
+		t.Errorf("backend group = %v", config.Groups["backend"])
+	}
+	if err := RenameProject(config, "core", "worker"); err == nil {
+		t.Fatal("expected an error when the new name is taken")
+	}
+}
+
+func TestProjectDir(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "pancake")
+	if path, err := ProjectDir(home, "api"); err != nil || path != filepath.Join(home, "api") {
+		t.Fatalf("ProjectDir(api) = %q, %v", path, err)
+	}
+	for _, name := range []string{"", ".", "..", "../other", "a/../.."} {
+		if path, err := ProjectDir(home, name); err == nil {
+			t.Errorf("ProjectDir(%q) = %q, want an error", name, path)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the pancake.yml format this version of pancake
+// reads and writes.
+const CurrentConfigVersion = 1
+
+// configMigration upgrades the top-level mapping of a pancake.yml by one
+// version. The 'version' key itself is updated by MigrateConfigData.
+type configMigration struct {
+	description string
+	apply       func(root *yamlv3.Node) error
+}
+
+// configMigrations[i] upgrades a file from version i to version i+1. Add new
+// migrations at the end and bump CurrentConfigVersion with them.
+var configMigrations = []configMigration{
+	{
+		description: "record the format version in a 'version' key",
+		apply:       func(root *yamlv3.Node) error { return nil },
+	},
+}
+
+// ConfigMigration describes the upgrade of a pancake.yml to the current
+// version.
+type ConfigMigration struct {
+	FromVersion int
+	Applied     []string
+	Original    []byte
+	Migrated    []byte
+	Backup      string
+}
+
+// ConfigFileVersion returns the 'version' of the config file data, 0 if it
+// has none.
+func ConfigFileVersion(data []byte) (int, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	if len(doc.Content) == 0 {
+		return 0, nil
+	}
+	value := yamlMappingValue(doc.Content[0], "version")
+	if value == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("'version' must be a whole number, got '%s'", value.Value)
+	}
+	return version, nil
+}
+
+// MigrateConfigData applies every migration data needs to reach
+// CurrentConfigVersion. Comments and key order are kept.
+func MigrateConfigData(data []byte) (*ConfigMigration, error) {
+	version, err := ConfigFileVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	migration := &ConfigMigration{FromVersion: version, Original: data, Migrated: data}
+	if version > CurrentConfigVersion {
+		return nil, fmt.Errorf(ConfigErrVersionTooNew, version, CurrentConfigVersion)
+	}
+	if version == CurrentConfigVersion {
+		return migration, nil
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		// An empty file has nothing to upgrade; loading it reports what
+		// is missing.
+		return migration, nil
+	}
+	if doc.Content[0].Kind != yamlv3.MappingNode {
+		return nil, errors.New("the top level of pancake.yml must be a mapping of settings")
+	}
+	canonical, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+	for ; version < CurrentConfigVersion; version++ {
+		step := configMigrations[version]
+		if err := step.apply(root); err != nil {
+			return nil, fmt.Errorf("migration to version %d (%s) failed: %w", version+1, step.description, err)
+		}
+		migration.Applied = append(migration.Applied, fmt.Sprintf("%d -> %d: %s", version, version+1, step.description))
+	}
+	edited, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, err
+	}
+	migration.Migrated, err = setConfigVersion(keepLayout(data, canonical, edited), CurrentConfigVersion)
+	if err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+// setConfigVersion writes 'version: <version>' into the config file data as
+// a text edit, so nothing else in the file moves: the value of an existing
+// 'version' key is replaced, otherwise the key is added below the comment
+// that opens the file.
+func setConfigVersion(data []byte, version int) ([]byte, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	value := strconv.Itoa(version)
+	if existing := yamlMappingValue(doc.Content[0], "version"); existing != nil {
+		line := lines[existing.Line-1]
+		column := existing.Column - 1
+		if column < len(line) && strings.HasPrefix(line[column:], existing.Value) {
+			lines[existing.Line-1] = line[:column] + value + line[column+len(existing.Value):]
+		} else {
+			lines[existing.Line-1] = line[:column] + value + "\n"
+		}
+	} else {
+		at := configHeaderEnd(lines)
+		lines = append(lines[:at], append([]string{"version: " + value + "\n"}, lines[at:]...)...)
+	}
+
+	migrated := []byte(strings.Join(lines, ""))
+	if written, err := ConfigFileVersion(migrated); err != nil || written != version {
+		return nil, fmt.Errorf("could not set 'version: %d' in pancake.yml", version)
+	}
+	return migrated, nil
+}
+
+// configHeaderEnd returns the index of the line below the header of a
+// config file: the comments, directives and '---' it starts with if a blank
+// line follows them, otherwise just the first comment line, which then is
+// the title above the comment of the first setting.
+func configHeaderEnd(lines []string) int {
+	header, documentStart := 0, 0
+	for header < len(lines) {
+		line := strings.TrimSpace(lines[header])
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "%") && line != "---" {
+			break
+		}
+		header++
+		if line == "---" || strings.HasPrefix(line, "%") {
+			documentStart = header
+		}
+	}
+	if header < len(lines) && header > 0 && strings.TrimSpace(lines[header]) == "" {
+		return header
+	}
+	return max(documentStart, min(header, 1))
+}
+
+// MigrateConfig upgrades the pancake.yml at configPath to the current
+// version. Unless dryRun is set the original is first copied to
+// pancake.yml.v<old version>.bak and the upgraded file is written in its
+// place.
+func MigrateConfig(configPath string, dryRun bool) (*ConfigMigration, error) {
+	if !dryRun {
+		unlock, err := LockFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	return migrateConfigFile(configPath, dryRun)
+}
+
+// migrateConfigFile is MigrateConfig for callers that hold the lock of
+// configPath already.
+func migrateConfigFile(configPath string, dryRun bool) (*ConfigMigration, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(fmt.Sprintf(ConfigErrNotFound, configPath))
+		}
+		return nil, errors.New(fmt.Sprintf(ConfigErrReadFailed, configPath, configPath))
+	}
+	migration, err := MigrateConfigData(data)
+	if err != nil || dryRun || len(migration.Applied) == 0 {
+		return migration, err
+	}
+
+	if err := backupConfigFile(configPath, migration); err != nil {
+		return nil, err
+	}
+	if err := WriteFileAtomic(configPath, migration.Migrated, 0644); err != nil {
+		return nil, fmt.Errorf("could not write pancake.yml at %s: %w", configPath, err)
+	}
+	return migration, nil
+}
+
+// backupConfigFile copies the original of an upgraded config file to
+// <configPath>.v<old version>.bak and records the name in migration.
+func backupConfigFile(configPath string, migration *ConfigMigration) error {
+	migration.Backup = fmt.Sprintf("%s.v%d.bak", configPath, migration.FromVersion)
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(configPath); err == nil {
+		perm = info.Mode().Perm() // a private pancake.yml gets a private backup
+	}
+	if err := WriteFileAtomic(migration.Backup, migration.Original, perm); err != nil {
+		return fmt.Errorf("could not back up pancake.yml to %s: %w", migration.Backup, err)
+	}
+	return nil
+}
+
+// upgradeConfigData checks that the config file data at configPath parses
+// and is in a version pancake understands, and upgrades it in memory.
+func upgradeConfigData(configPath string, data []byte) (*ConfigMigration, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, errors.New(fmt.Sprintf(ConfigErrParseFailed, err.Error(), configPath))
+	}
+	version, err := ConfigFileVersion(data)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(ConfigErrMigrationFailed, err.Error()))
+	}
+	if version > CurrentConfigVersion {
+		return nil, errors.New(fmt.Sprintf(ConfigErrVersionTooNew, version, CurrentConfigVersion))
+	}
+	migration, err := MigrateConfigData(data)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(ConfigErrMigrationFailed, err.Error()))
+	}
+	return migration, nil
+}
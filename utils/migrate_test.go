@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfigData_AddsVersion(t *testing.T) {
+	original := "# My setup\nhome: $HOME/pancake # where code lives\ntools: [git]\n"
+	migration, err := MigrateConfigData([]byte(original))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if migration.FromVersion != 0 || len(migration.Applied) != CurrentConfigVersion {
+		t.Errorf("got from %d with steps %v", migration.FromVersion, migration.Applied)
+	}
+	migrated := string(migration.Migrated)
+	for _, want := range []string{"# My setup", "version: 1\n", "home: $HOME/pancake # where code lives", "tools: [git]"} {
+		if !strings.Contains(migrated, want) {
+			t.Errorf("expected %q in:\n%s", want, migrated)
+		}
+	}
+	if version, err := ConfigFileVersion(migration.Migrated); err != nil || version != CurrentConfigVersion {
+		t.Errorf("migrated file has version %d (%v)", version, err)
+	}
+}
+
+func TestMigrateConfigData_Current(t *testing.T) {
+	original := fmt.Sprintf("version: %d\nhome: /tmp\n", CurrentConfigVersion)
+	migration, err := MigrateConfigData([]byte(original))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migration.Applied) != 0 || string(migration.Migrated) != original {
+		t.Errorf("expected no changes, got %v:\n%s", migration.Applied, migration.Migrated)
+	}
+}
+
+func TestGetConfig_EmptyFile(t *testing.T) {
+	for _, contents := range []string{"", "# nothing here yet\n"} {
+		writeConfig(t, contents)
+		_, err := GetConfig()
+		if err == nil || !strings.Contains(err.Error(), "'home' is empty") {
+			t.Errorf("%q: expected the 'home' guidance, got %v", contents, err)
+		}
+	}
+}
+
+func TestMigrateConfigData_TooNew(t *testing.T) {
+	_, err := MigrateConfigData([]byte(fmt.Sprintf("version: %d\n", CurrentConfigVersion+1)))
+	if err == nil || !strings.Contains(err.Error(), "Upgrade pancake") {
+		t.Errorf("expected a too-new error, got %v", err)
+	}
+	if _, err := ConfigFileVersion([]byte("version: two\n")); err == nil {
+		t.Error("expected an error for a non-numeric version")
+	}
+}
+
+func TestDefaultYMLContent_IsCurrent(t *testing.T) {
+	if version, err := ConfigFileVersion([]byte(DefaultYMLContent)); err != nil || version != CurrentConfigVersion {
+		t.Errorf("DefaultYMLContent has version %d (%v), want %d", version, err, CurrentConfigVersion)
+	}
+}
+
+func TestMigrateConfig_DryRunAndBackup(t *testing.T) {
+	configPath := writeConfig(t, validConfig)
+
+	migration, err := MigrateConfig(configPath, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != validConfig {
+		t.Error("dry run changed pancake.yml")
+	}
+	if !strings.Contains(UnifiedDiff(string(migration.Original), string(migration.Migrated), "a", "b"), "+version: 1\n") {
+		t.Error("expected the diff to add the version")
+	}
+
+	migration, err = MigrateConfig(configPath, false)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if backup, err := os.ReadFile(configPath + ".v0.bak"); err != nil || string(backup) != validConfig || migration.Backup != configPath+".v0.bak" {
+		t.Errorf("backup %s not written with the original contents: %v", migration.Backup, err)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.HasPrefix(string(data), "version: 1\n") {
+		t.Errorf("pancake.yml not upgraded:\n%s", data)
+	}
+}
+
+func TestMigrateConfigData_OnlyAddsVersionUnderHeader(t *testing.T) {
+	baseline := strings.Replace(DefaultYMLContent, "version: 1 # Format version, upgraded by pancake config migrate\n", "", 1)
+	migration, err := MigrateConfigData([]byte(baseline))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(baseline, "\n", 2)
+	if want := lines[0] + "\nversion: 1\n" + lines[1]; string(migration.Migrated) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", migration.Migrated, want)
+	}
+
+	for original, want := range map[string]string{
+		"home: /srv\n":                       "version: 1\nhome: /srv\n",
+		"# Title\n# More\n\nhome: /srv\n":    "# Title\n# More\nversion: 1\n\nhome: /srv\n",
+		"# Title\n---\n# home\nhome: /srv\n": "# Title\n---\nversion: 1\n# home\nhome: /srv\n",
+		"home: /srv\nversion: 0 # old\n":     "home: /srv\nversion: 1 # old\n",
+		"home: /srv\nversion: \"0\"\n":       "home: /srv\nversion: 1\n",
+	} {
+		migration, err := MigrateConfigData([]byte(original))
+		if err != nil {
+			t.Errorf("%q: %v", original, err)
+		} else if string(migration.Migrated) != want {
+			t.Errorf("%q became %q, want %q", original, migration.Migrated, want)
+		}
+	}
+}
+
+func TestGetConfig_UpgradesInMemoryOnly(t *testing.T) {
+	configPath := writeConfig(t, validConfig)
+	var warnings bytes.Buffer
+	ConfigWarnings = &warnings
+	t.Cleanup(func() { ConfigWarnings = os.Stderr })
+
+	for i := 0; i < 2; i++ {
+		config, err := GetConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.Version != CurrentConfigVersion {
+			t.Errorf("Version = %d, want %d", config.Version, CurrentConfigVersion)
+		}
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != validConfig {
+		t.Errorf("GetConfig changed pancake.yml:\n%s", data)
+	}
+	if CheckExists(configPath + ".v0.bak") {
+		t.Error("GetConfig wrote a backup")
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("GetConfig should load an old file quietly, got %q", warnings.String())
+	}
+
+	if err := ModifyConfig(func(cfg *Config) error {
+		cfg.CodeEditor = "vim"
+		return nil
+	}); err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+	if backup, err := os.ReadFile(configPath + ".v0.bak"); err != nil || string(backup) != validConfig {
+		t.Errorf("expected a backup of the original: %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.HasPrefix(string(data), "version: 1\n") || !strings.Contains(string(data), "code_editor: vim") {
+		t.Errorf("pancake.yml not upgraded and changed:\n%s", data)
+	}
+	if !strings.Contains(warnings.String(), "Upgraded pancake.yml from version 0 to 1 (backup: "+configPath+".v0.bak)") {
+		t.Errorf("unexpected message %q", warnings.String())
+	}
+
+	warnings.Reset()
+	if err := ModifyConfig(func(cfg *Config) error {
+		cfg.CodeEditor = "nano"
+		return nil
+	}); err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("an up to date file was reported as upgraded: %q", warnings.String())
+	}
+
+	writeConfig(t, fmt.Sprintf("version: %d\n", CurrentConfigVersion+1)+validConfig)
+	if _, err := GetConfig(); err == nil || !strings.Contains(err.Error(), "only understands up to version") {
+		t.Errorf("expected a too-new error, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// TaskResult is the outcome of running one project's task in RunParallel.
+type TaskResult struct {
+	Name     string
+	Summary  string // one-line outcome reported by the task, e.g. "3 new commits"
+	Err      error
+	Duration time.Duration
+}
+
+// RunParallel runs task for every name with at most jobs running at once.
+// A name only starts once the names it depends on (per dependsOn, which may
+// be nil) have finished; if one of them failed it is skipped. Each task writes
+// into its own buffer, which is copied to out with every line prefixed by
+// "[name] " once the task finishes, so the output of concurrent projects never
+// interleaves. Results are returned in the order of names.
+func RunParallel(names []string, jobs int, dependsOn map[string][]string, out io.Writer, task func(name string, output io.Writer) (string, error)) []TaskResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	waiting := make([]int, len(names))
+	dependents := make([][]int, len(names))
+	for i, name := range names {
+		for _, dep := range dependsOn[name] {
+			if j, ok := index[dep]; ok {
+				waiting[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	results := make([]TaskResult, len(names))
+	queue := make(chan int, len(names))
+	done := make(chan int)
+	var outMu sync.Mutex
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(jobs, len(names)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				var buf bytes.Buffer
+				start := time.Now()
+				summary, err := task(names[i], &buf)
+				results[i] = TaskResult{Name: names[i], Summary: summary, Err: err, Duration: time.Since(start)}
+
+				outMu.Lock()
+				writePrefixed(out, "["+names[i]+"] ", &buf)
+				outMu.Unlock()
+				done <- i
+			}
+		}()
+	}
+
+	running := 0
+	failedDep := make([]string, len(names))
+	var finish func(i int)
+	finish = func(i int) {
+		for _, d := range dependents[i] {
+			if results[i].Err != nil && failedDep[d] == "" {
+				failedDep[d] = names[i]
+			}
+			waiting[d]--
+			if waiting[d] > 0 {
+				continue
+			}
+			if failedDep[d] != "" {
+				results[d] = TaskResult{Name: names[d], Err: fmt.Errorf("skipped because dependency %s failed", failedDep[d])}
+				finish(d)
+				continue
+			}
+			running++
+			queue <- d
+		}
+	}
+	for i := range names {
+		if waiting[i] == 0 {
+			running++
+			queue <- i
+		}
+	}
+	for running > 0 {
+		i := <-done
+		running--
+		finish(i)
+	}
+	close(queue)
+	wg.Wait()
+
+	// Anything left waiting is part of a dependency cycle.
+	for i := range names {
+		if results[i].Name == "" {
+			results[i] = TaskResult{Name: names[i], Err: fmt.Errorf("skipped because of a dependency cycle")}
+		}
+	}
+	return results
+}
+
+func writePrefixed(out io.Writer, prefix string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fmt.Fprintf(out, "%s%s\n", prefix, scanner.Text())
+	}
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunParallel_LimitsConcurrency(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	var running, peak int32
+	task := func(name string, output io.Writer) (string, error) {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return "", nil
+	}
+	RunParallel(names, 2, nil, io.Discard, task)
+	if peak > 2 {
+		t.Fatalf("expected at most 2 concurrent tasks, saw %d", peak)
+	}
+}
+
+func TestRunParallel_PrefixesOutputAndKeepsOrder(t *testing.T) {
+	var out bytes.Buffer
+	results := RunParallel([]string{"api", "web"}, 2, nil, &out, func(name string, output io.Writer) (string, error) {
+		fmt.Fprintf(output, "line 1 of %s\nline 2 of %s\n", name, name)
+		if name == "web" {
+			return "", errors.New("boom")
+		}
+		return "done", nil
+	})
+	if results[0].Name != "api" || results[0].Err != nil || results[0].Summary != "done" {
+		t.Fatalf("unexpected result for api: %+v", results[0])
+	}
+	if results[1].Name != "web" || results[1].Err == nil {
+		t.Fatalf("unexpected result for web: %+v", results[1])
+	}
+	text := out.String()
+	for _, want := range []string{"[api] line 1 of api\n[api] line 2 of api\n", "[web] line 1 of web\n[web] line 2 of web\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("output should contain %q as a block, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestRunParallel_RespectsDependencies(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	dependsOn := map[string][]string{"web": {"api"}, "api": {"db"}, "docs": {"broken"}}
+	results := RunParallel([]string{"web", "api", "db", "broken", "docs"}, 4, dependsOn, io.Discard, func(name string, output io.Writer) (string, error) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		if name == "broken" {
+			return "", errors.New("boom")
+		}
+		return "", nil
+	})
+	position := make(map[string]int)
+	for i, name := range order {
+		position[name] = i
+	}
+	if position["db"] > position["api"] || position["api"] > position["web"] {
+		t.Fatalf("dependencies should run first, got order %v", order)
+	}
+	if _, ran := position["docs"]; ran {
+		t.Fatalf("docs should be skipped after its dependency failed, got order %v", order)
+	}
+	if results[4].Err == nil || !strings.Contains(results[4].Err.Error(), "broken") {
+		t.Fatalf("expected docs to report the failed dependency, got %+v", results[4])
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PortListener describes the process listening on a local TCP port. PID is 0
+// when the port is bound but the owning process cannot be determined (for
+// example because it belongs to another user).
+type PortListener struct {
+	Port int
+	PID  int
+}
+
+func ParsePort(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("'%s' is not a valid TCP port (1-65535)", value)
+	}
+	return port, nil
+}
+
+// FindPortListener returns the process listening on port, or nil if the port
+// is free. It reads the OS socket table and falls back to a bind probe when
+// the table is not available.
+func FindPortListener(port int) (*PortListener, error) {
+	listener, err := lookupPortListener(port)
+	if err == nil {
+		return listener, nil
+	}
+	if portBound(port) {
+		return &PortListener{Port: port}, nil
+	}
+	return nil, nil
+}
+
+// ListenerBelongsTo reports whether the listening process is pid itself or
+// part of the process tree pancake started as pid.
+func ListenerBelongsTo(listener *PortListener, pid int) bool {
+	if listener == nil || listener.PID == 0 || pid <= 0 {
+		return false
+	}
+	return listener.PID == pid || processInTree(listener.PID, pid)
+}
+
+func portBound(port int) bool {
+	probe, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return true
+	}
+	probe.Close()
+	return false
+}
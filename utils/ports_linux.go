@@ -0,0 +1,83 @@
+//go:build linux
+
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const tcpListenState = "0A"
+
+// lookupPortListener finds the listening socket for port in /proc/net/tcp{,6}
+// and maps its inode back to a PID through /proc/<pid>/fd.
+func lookupPortListener(port int) (*PortListener, error) {
+	inodes := make(map[string]bool)
+	var readErr error
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := collectListenInodes(table, port, inodes); err != nil {
+			readErr = err
+		}
+	}
+	if len(inodes) == 0 {
+		if readErr != nil {
+			return nil, readErr
+		}
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return &PortListener{Port: port}, nil
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")] {
+				return &PortListener{Port: port, PID: pid}, nil
+			}
+		}
+	}
+	return &PortListener{Port: port}, nil
+}
+
+func collectListenInodes(table string, port int, inodes map[string]bool) error {
+	file, err := os.Open(table)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	wantPort := fmt.Sprintf("%04X", port)
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+		if colon := strings.LastIndexByte(fields[1], ':'); colon >= 0 && fields[1][colon+1:] == wantPort {
+			inodes[fields[9]] = true
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,28 @@
+//go:build !linux && !windows
+
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// lookupPortListener asks lsof(8) which process listens on port.
+func lookupPortListener(port int) (*PortListener, error) {
+	output, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-t").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && len(output) == 0 {
+			// lsof exits 1 when nothing matches.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not run lsof: %w", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	pid, _ := strconv.Atoi(fields[0])
+	return &PortListener{Port: port, PID: pid}, nil
+}
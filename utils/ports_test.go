@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net"
+	"os"
+	"testing"
+)
+
+func TestParsePort(t *testing.T) {
+	if port, err := ParsePort("3000"); err != nil || port != 3000 {
+		t.Fatalf("ParsePort(3000) = %d, %v", port, err)
+	}
+	for _, bad := range []string{"", "abc", "0", "70000"} {
+		if _, err := ParsePort(bad); err == nil {
+			t.Errorf("ParsePort(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFindPortListener_Listening(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	found, err := FindPortListener(port)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if found == nil {
+		t.Fatalf("expected a listener on port %d", port)
+	}
+	if found.PID != 0 && found.PID != os.Getpid() {
+		t.Fatalf("listener PID = %d, want %d", found.PID, os.Getpid())
+	}
+}
+
+func TestFindPortListener_Free(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	found, err := FindPortListener(port)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if found != nil {
+		t.Fatalf("expected port %d to be free, got %+v", port, found)
+	}
+}
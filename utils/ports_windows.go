@@ -0,0 +1,29 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// lookupPortListener parses `netstat -ano` to find the process listening on port.
+func lookupPortListener(port int) (*PortListener, error) {
+	output, err := exec.Command("netstat", "-ano").Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not run netstat: %w", err)
+	}
+	suffix := fmt.Sprintf(":%d", port)
+	for _, line := range strings.Split(string(output), "\n") {
+		// Proto  Local Address  Foreign Address  State  PID
+		fields := strings.Fields(line)
+		if len(fields) != 5 || fields[0] != "TCP" || fields[3] != "LISTENING" || !strings.HasSuffix(fields[1], suffix) {
+			continue
+		}
+		pid, _ := strconv.Atoi(fields[4])
+		return &PortListener{Port: port, PID: pid}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LogsDirName      = ".logs"
+	startupGraceTime = 500 * time.Millisecond
+)
+
+// StartDetachedProcess runs cmdStr in dir as a background child in its own
+// process group and returns its PID. The child is started through a pancake
+// supervisor process (see RunSupervisor) that writes its stdout/stderr to the
+// rotating log at logPath, so both keep running after pancake exits. A nil
+// env inherits pancake's environment.
+func StartDetachedProcess(cmdStr, dir, logPath string, env []string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("could not locate the pancake executable: %w", err)
+	}
+	command := exec.Command(executable, logPath, cmdStr)
+	command.Dir = dir
+	if env == nil {
+		env = os.Environ()
+	}
+	command.Env = append(env, supervisorEnv+"=1")
+	handshake, err := command.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("could not start '%s': %w", cmdStr, err)
+	}
+	detachProcess(command)
+	if err := command.Start(); err != nil {
+		return 0, fmt.Errorf("could not start '%s': %w", cmdStr, err)
+	}
+	defer command.Process.Release()
+
+	// The supervisor reports the workload's PID, then either "running" once
+	// it survived the startup grace time or how it exited, so commands that
+	// fail straight away (typos, missing binaries) are reported instead of
+	// being recorded as running.
+	pid := 0
+	scanner := bufio.NewScanner(handshake)
+	for scanner.Scan() {
+		status, detail, _ := strings.Cut(scanner.Text(), " ")
+		switch status {
+		case "started":
+			pid, _ = strconv.Atoi(detail)
+		case "running":
+			return pid, nil
+		case "exited":
+			if detail != exitedSuccessfully {
+				return 0, fmt.Errorf("'%s' exited immediately: %s. See %s for its output", cmdStr, detail, logPath)
+			}
+			return pid, nil
+		case "error":
+			return 0, fmt.Errorf("could not start '%s': %s", cmdStr, detail)
+		}
+	}
+	return 0, fmt.Errorf("could not start '%s': the pancake supervisor exited unexpectedly", cmdStr)
+}
+
+// StopProcessTree asks the process group led by pid to terminate and
+// force-kills it if it is still alive after the grace period.
+func StopProcessTree(pid int, grace time.Duration) error {
+	if !processTreeAlive(pid) {
+		return nil
+	}
+	if err := terminateProcessTree(pid, false); err != nil {
+		return fmt.Errorf("could not stop process %d: %w", pid, err)
+	}
+	if waitForExit(pid, grace) {
+		return nil
+	}
+	if err := terminateProcessTree(pid, true); err != nil {
+		return fmt.Errorf("could not kill process %d: %w", pid, err)
+	}
+	if !waitForExit(pid, 5*time.Second) {
+		return fmt.Errorf("process %d is still running after being killed", pid)
+	}
+	return nil
+}
+
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if !processTreeAlive(pid) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+const (
+	ProcessRunning = "Running"
+	ProcessStopped = "Stopped"
+	ProcessStale   = "Stale"
+)
+
+// errProcessExited is returned by InspectProcess for processes that are gone
+// (or zombies waiting to be reaped).
+var errProcessExited = errors.New("process has exited")
+
+// ProcessStats describes a running project's process group as reported by the OS.
+type ProcessStats struct {
+	StartTime   uint64 // platform-specific start marker, fixed for the life of the process
+	StartedAt   time.Time
+	Cmdline     string
+	MemoryBytes uint64  // resident memory of the whole process group
+	CPUPercent  float64 // average CPU usage of the process group since it started
+}
+
+// NewProcessRecord snapshots the identity of a process pancake just started.
+func NewProcessRecord(pid int) ProcessRecord {
+	record := ProcessRecord{PID: pid}
+	if stats, err := InspectProcess(pid); err == nil {
+		record.StartTime = stats.StartTime
+		record.Cmdline = stats.Cmdline
+	}
+	return record
+}
+
+// CheckProcess reports whether record still refers to the process pancake
+// started: Running, Stopped (the PID is gone) or Stale (the PID was reused by
+// another process). Stats are only returned for running processes.
+func CheckProcess(record ProcessRecord) (string, *ProcessStats) {
+	if !ProcessAlive(record.PID) {
+		return ProcessStopped, nil
+	}
+	stats, err := InspectProcess(record.PID)
+	if errors.Is(err, errProcessExited) {
+		return ProcessStopped, nil
+	}
+	if err != nil {
+		return ProcessRunning, nil
+	}
+	if record.StartTime != 0 && stats.StartTime != 0 && record.StartTime != stats.StartTime {
+		return ProcessStale, nil
+	}
+	if record.StartTime == 0 && record.Cmdline != "" && stats.Cmdline != "" && record.Cmdline != stats.Cmdline {
+		return ProcessStale, nil
+	}
+	return ProcessRunning, stats
+}
+
+func FormatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%02dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%02dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%02ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
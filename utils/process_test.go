@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets StartDetachedProcess re-execute the test binary as the
+// project supervisor, the same way it re-executes pancake.
+func TestMain(m *testing.M) {
+	if SupervisorRequested() {
+		os.Exit(RunSupervisor())
+	}
+	os.Exit(m.Run())
+}
+
+func TestStartDetachedProcess_WritesLog(t *testing.T) {
+	dir := t.TempDir()
+	logPath := ProjectLogPath(dir, "demo")
+	pid, err := StartDetachedProcess("echo hello-from-demo", dir, logPath, nil)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if pid <= 0 {
+		t.Fatalf("expected a pid, got %d", pid)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if !strings.Contains(string(data), "hello-from-demo") {
+		t.Fatalf("log should contain command output, got: %s", data)
+	}
+}
+
+func TestStartDetachedProcess_ImmediateFailure(t *testing.T) {
+	dir := t.TempDir()
+	_, err := StartDetachedProcess("exit 3", dir, filepath.Join(dir, "fail.log"), nil)
+	if err == nil {
+		t.Fatal("expected error for a command that exits non-zero straight away")
+	}
+}
+
+func TestCopyLogLines_SplitsLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "long.log")
+	log, err := OpenRotatingLog(path, LogMaxBytes, LogMaxBackups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("y", 2*1024*1024)
+	copyLogLines(strings.NewReader("first\n"+long+"\nlast"), log)
+	log.Close()
+
+	entries, err := ReadLogEntries(path, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, entry := range entries {
+		texts = append(texts, entry.Text)
+	}
+	if len(texts) < 3 || texts[0] != "first" || texts[len(texts)-1] != "last" {
+		t.Fatalf("unexpected log lines around the long one: %d lines", len(texts))
+	}
+	if got := strings.Join(texts[1:len(texts)-1], ""); got != long {
+		t.Errorf("the long line lost %d bytes", len(long)-len(got))
+	}
+}
+
+func TestStopProcessTree_StopsDetachedProcess(t *testing.T) {
+	dir := t.TempDir()
+	pid, err := StartDetachedProcess("sleep 30", dir, filepath.Join(dir, "sleep.log"), nil)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !ProcessAlive(pid) {
+		t.Fatalf("process %d should be running", pid)
+	}
+	if err := StopProcessTree(pid, 2*time.Second); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if ProcessAlive(pid) {
+		t.Fatalf("process %d should have been stopped", pid)
+	}
+}
+
+func TestStopProcessTree_NotRunning(t *testing.T) {
+	if err := StopProcessTree(0, time.Second); err != nil {
+		t.Fatalf("stopping a missing process should be a no-op, got: %v", err)
+	}
+}
+
+func TestCheckProcess_Running(t *testing.T) {
+	dir := t.TempDir()
+	pid, err := StartDetachedProcess("sleep 30", dir, filepath.Join(dir, "sleep.log"), nil)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer StopProcessTree(pid, time.Second)
+	record := NewProcessRecord(pid)
+	status, stats := CheckProcess(record)
+	if status != ProcessRunning {
+		t.Fatalf("status = %s, want %s", status, ProcessRunning)
+	}
+	if stats == nil || stats.StartedAt.IsZero() {
+		t.Fatalf("expected stats with a start time, got %+v", stats)
+	}
+}
+
+func TestCheckProcess_PIDReused(t *testing.T) {
+	record := NewProcessRecord(os.Getpid())
+	if record.StartTime == 0 {
+		t.Skip("process start time not available on this platform")
+	}
+	record.StartTime++
+	if status, _ := CheckProcess(record); status != ProcessStale {
+		t.Fatalf("status = %s, want %s", status, ProcessStale)
+	}
+}
+
+func TestCheckProcess_Stopped(t *testing.T) {
+	dir := t.TempDir()
+	pid, err := StartDetachedProcess("sleep 30", dir, filepath.Join(dir, "sleep.log"), nil)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	record := NewProcessRecord(pid)
+	if err := StopProcessTree(pid, time.Second); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if status, _ := CheckProcess(record); status != ProcessStopped {
+		t.Fatalf("status = %s, want %s", status, ProcessStopped)
+	}
+}
+
+func TestLoadProjectProcesses_LegacyFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PIDsFileName), []byte(`{"demo":1234}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var loaded map[string]ProcessRecord
+	if err := LoadProjectProcesses(dir, &loaded); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded["demo"].PID != 1234 {
+		t.Fatalf("legacy pid not read: %v", loaded)
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	cases := map[time.Duration]string{
+		42 * time.Second:              "42s",
+		3*time.Minute + 5*time.Second: "3m05s",
+		2*time.Hour + 7*time.Minute:   "2h07m",
+		50*time.Hour + 30*time.Minute: "2d02h",
+	}
+	for in, want := range cases {
+		if got := FormatUptime(in); got != want {
+			t.Errorf("FormatUptime(%v) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	if got := FormatBytes(512); got != "512 B" {
+		t.Errorf("FormatBytes(512) = %s", got)
+	}
+	if got := FormatBytes(3 * 1024 * 1024); got != "3.0 MiB" {
+		t.Errorf("FormatBytes(3MiB) = %s", got)
+	}
+}
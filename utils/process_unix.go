@@ -0,0 +1,65 @@
+//go:build !windows
+
+package utils
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts the command in a new session so it gets its own
+// process group and survives the terminal (or SSH connection) closing.
+func detachProcess(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// newProcessGroup puts the command in a process group of its own so the
+// whole tree can be signalled at once.
+func newProcessGroup(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processTreeAlive reports whether the leader or any other member of the
+// process group led by pid is still running.
+func processTreeAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(-pid, 0)
+	if err == nil || errors.Is(err, syscall.EPERM) {
+		return true
+	}
+	return ProcessAlive(pid)
+}
+
+// terminateProcessTree signals the whole process group led by pid. Processes
+// that are not group leaders (e.g. started with --terminal) are signalled directly.
+func terminateProcessTree(pid int, force bool) error {
+	signal := syscall.SIGTERM
+	if force {
+		signal = syscall.SIGKILL
+	}
+	err := syscall.Kill(-pid, signal)
+	if errors.Is(err, syscall.ESRCH) {
+		err = syscall.Kill(pid, signal)
+	}
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// processInTree reports whether pid is a member of the process group led by root.
+func processInTree(pid, root int) bool {
+	pgid, err := syscall.Getpgid(pid)
+	return err == nil && pgid == root
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+package utils
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// detachProcess starts the command in a new process group without a console
+// so it keeps running after pancake exits.
+func detachProcess(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess}
+}
+
+// newProcessGroup puts the command in a process group of its own.
+func newProcessGroup(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
+
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActive
+}
+
+// processTreeAlive only checks pid itself; taskkill /T takes care of the children.
+func processTreeAlive(pid int) bool {
+	return ProcessAlive(pid)
+}
+
+// terminateProcessTree uses taskkill to stop pid and all of its children.
+func terminateProcessTree(pid int, force bool) error {
+	args := []string{"/T", "/PID", strconv.Itoa(pid)}
+	if force {
+		args = append([]string{"/F"}, args...)
+	}
+	return exec.Command("taskkill", args...).Run()
+}
+
+// processInTree reports whether root is pid or one of its ancestors.
+func processInTree(pid, root int) bool {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(snapshot)
+	parents := make(map[uint32]uint32)
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		parents[entry.ProcessID] = entry.ParentProcessID
+	}
+	current := uint32(pid)
+	for depth := 0; depth < 64 && current != 0; depth++ {
+		if current == uint32(root) {
+			return true
+		}
+		current = parents[current]
+	}
+	return false
+}
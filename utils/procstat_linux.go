@@ -0,0 +1,116 @@
+//go:build linux
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, which is 100 on every Linux platform Go supports.
+const clockTicks = 100
+
+type procStat struct {
+	state     byte
+	pgrp      int
+	cpuTicks  uint64
+	startTime uint64
+	rssPages  uint64
+}
+
+// readProcStat parses /proc/<pid>/stat. The command name is skipped by
+// splitting after its closing parenthesis, since it may contain spaces.
+func readProcStat(pid int) (*procStat, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errProcessExited
+		}
+		return nil, err
+	}
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 || end+2 > len(data) {
+		return nil, fmt.Errorf("unexpected format in /proc/%d/stat", pid)
+	}
+	// fields[0] is field 3 (state) in proc(5).
+	fields := strings.Fields(string(data[end+2:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("unexpected format in /proc/%d/stat", pid)
+	}
+	stat := &procStat{state: fields[0][0]}
+	stat.pgrp, _ = strconv.Atoi(fields[2])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	stat.cpuTicks = utime + stime
+	stat.startTime, _ = strconv.ParseUint(fields[19], 10, 64)
+	stat.rssPages, _ = strconv.ParseUint(fields[21], 10, 64)
+	return stat, nil
+}
+
+func bootTime() (time.Time, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("btime not found in /proc/stat")
+}
+
+// InspectProcess reads the identity of pid and the resource usage of the
+// process group it leads from /proc.
+func InspectProcess(pid int) (*ProcessStats, error) {
+	leader, err := readProcStat(pid)
+	if err != nil {
+		return nil, err
+	}
+	if leader.state == 'Z' || leader.state == 'X' {
+		return nil, errProcessExited
+	}
+	stats := &ProcessStats{StartTime: leader.startTime}
+	if boot, err := bootTime(); err == nil {
+		stats.StartedAt = boot.Add(time.Duration(leader.startTime) * time.Second / clockTicks)
+	}
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		stats.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+
+	cpuTicks := leader.cpuTicks
+	rssPages := leader.rssPages
+	if leader.pgrp == pid {
+		entries, _ := os.ReadDir("/proc")
+		for _, entry := range entries {
+			memberPID, err := strconv.Atoi(entry.Name())
+			if err != nil || memberPID == pid {
+				continue
+			}
+			member, err := readProcStat(memberPID)
+			if err != nil || member.pgrp != pid {
+				continue
+			}
+			cpuTicks += member.cpuTicks
+			rssPages += member.rssPages
+		}
+	}
+	stats.MemoryBytes = rssPages * uint64(os.Getpagesize())
+	if !stats.StartedAt.IsZero() {
+		if elapsed := time.Since(stats.StartedAt).Seconds(); elapsed > 0 {
+			stats.CPUPercent = float64(cpuTicks) / clockTicks / elapsed * 100
+		}
+	}
+	return stats, nil
+}
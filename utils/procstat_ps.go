@@ -0,0 +1,63 @@
+//go:build !linux && !windows
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InspectProcess asks ps(1) for the identity of pid and the resource usage
+// of the process group it leads; BSD and macOS have no /proc to read.
+func InspectProcess(pid int) (*ProcessStats, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=,pgid=,stat=,rss=,%cpu=,lstart=,command=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not run ps: %w", err)
+	}
+	var stats *ProcessStats
+	var memoryKB uint64
+	var cpuPercent float64
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		// pid pgid stat rss %cpu <lstart: 5 words> command...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		memberPID, _ := strconv.Atoi(fields[0])
+		groupID, _ := strconv.Atoi(fields[1])
+		if memberPID != pid && groupID != pid {
+			continue
+		}
+		rss, _ := strconv.ParseUint(fields[3], 10, 64)
+		cpu, _ := strconv.ParseFloat(fields[4], 64)
+		memoryKB += rss
+		cpuPercent += cpu
+		if memberPID != pid {
+			continue
+		}
+		if strings.HasPrefix(fields[2], "Z") {
+			return nil, errProcessExited
+		}
+		startedAt, err := time.ParseInLocation("Mon Jan _2 15:04:05 2006", strings.Join(fields[5:10], " "), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse start time of process %d: %w", pid, err)
+		}
+		stats = &ProcessStats{
+			StartTime: uint64(startedAt.Unix()),
+			StartedAt: startedAt,
+			Cmdline:   strings.Join(fields[10:], " "),
+		}
+	}
+	if stats == nil {
+		return nil, errProcessExited
+	}
+	stats.MemoryBytes = memoryKB * 1024
+	stats.CPUPercent = cpuPercent
+	return stats, nil
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package utils
+
+import (
+	"syscall"
+	"time"
+)
+
+// InspectProcess only reports the start time on Windows; memory and command
+// line are not available without the psapi/WMI bindings.
+func InspectProcess(pid int) (*ProcessStats, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return nil, errProcessExited
+	}
+	defer syscall.CloseHandle(handle)
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return nil, err
+	}
+	startedAt := time.Unix(0, creation.Nanoseconds())
+	stats := &ProcessStats{
+		StartTime: uint64(creation.Nanoseconds()),
+		StartedAt: startedAt,
+	}
+	if elapsed := time.Since(startedAt).Seconds(); elapsed > 0 {
+		cpu := filetimeDuration(kernel) + filetimeDuration(user)
+		stats.CPUPercent = cpu.Seconds() / elapsed * 100
+	}
+	return stats, nil
+}
+
+// filetimeDuration converts a FILETIME holding an interval (not a date) into
+// a Duration; FILETIME counts 100-nanosecond units.
+func filetimeDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
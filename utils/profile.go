@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultProfileName selects the top-level settings of pancake.yml rather
+// than one of its profiles.
+const DefaultProfileName = "default"
+
+// ProfileOverride is the profile given with the --profile flag. It takes
+// precedence over the 'profile' key of pancake.yml.
+var ProfileOverride string
+
+// Profile is an entry of 'profiles:', a separate set of projects with its
+// own home directory, tools and AI provider. Home and DefaultAI fall back
+// to the top-level settings when unset; the other fields belong to the
+// profile alone.
+type Profile struct {
+	Home      string              `yaml:"home,omitempty"`
+	DefaultAI string              `yaml:"default_ai,omitempty"`
+	Tools     []string            `yaml:"tools,omitempty"`
+	Projects  map[string]Project  `yaml:"projects,omitempty"`
+	Groups    map[string][]string `yaml:"groups,omitempty"`
+}
+
+// ActiveProfile returns the name of the profile config was loaded with, or
+// DefaultProfileName if it uses the top-level settings.
+func (c *Config) ActiveProfile() string {
+	if c.activeProfile == "" {
+		return DefaultProfileName
+	}
+	return c.activeProfile
+}
+
+// ProfileNames returns DefaultProfileName followed by the names of the
+// profiles in config, sorted.
+func ProfileNames(config *Config) []string {
+	return append([]string{DefaultProfileName}, sortedKeys(config.Profiles)...)
+}
+
+// applyProfile replaces the top-level settings of config with those of the
+// selected profile: the --profile flag, or else the 'profile' key. The
+// replaced settings are kept so fileView can write changes back to the
+// profile.
+func (c *Config) applyProfile() error {
+	name := ProfileOverride
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" || name == DefaultProfileName {
+		return nil
+	}
+	profile, exists := c.Profiles[name]
+	if !exists {
+		if ProfileOverride == "" {
+			// Reported by ValidateConfig, so 'pancake profile use' can fix it.
+			return nil
+		}
+		return errors.New(fmt.Sprintf(ConfigErrProfileUnknown, name, strings.Join(ProfileNames(c), ", ")))
+	}
+
+	c.base = &Profile{Home: c.Home, DefaultAI: c.DefaultAI, Tools: c.Tools, Projects: c.Projects, Groups: c.Groups}
+	c.activeProfile = name
+	if profile.Home != "" {
+		c.Home = profile.Home
+	}
+	if profile.DefaultAI != "" {
+		c.DefaultAI = profile.DefaultAI
+	}
+	c.Tools, c.Projects, c.Groups = profile.Tools, profile.Projects, profile.Groups
+	return nil
+}
+
+// fileView returns config as it is laid out in pancake.yml, with the
+// settings of the active profile moved back under 'profiles:'.
+func (c *Config) fileView() *Config {
+	if c.activeProfile == "" {
+		return c
+	}
+	view := *c
+	view.Profiles = make(map[string]Profile, len(c.Profiles))
+	for name, profile := range c.Profiles {
+		view.Profiles[name] = profile
+	}
+	profile := view.Profiles[c.activeProfile]
+	profile.Home, profile.DefaultAI = c.Home, c.DefaultAI
+	profile.Tools, profile.Projects, profile.Groups = c.Tools, c.Projects, c.Groups
+	view.Profiles[c.activeProfile] = profile
+	view.Home, view.DefaultAI = c.base.Home, c.base.DefaultAI
+	view.Tools, view.Projects, view.Groups = c.base.Tools, c.base.Projects, c.base.Groups
+	return &view
+}
+
+// settingPath returns where the setting at path is written in pancake.yml,
+// which is under 'profiles:' for the settings of the active profile.
+func (c *Config) settingPath(path []string) []string {
+	if c.activeProfile == "" || len(path) == 0 {
+		return path
+	}
+	profile := c.Profiles[c.activeProfile]
+	switch path[0] {
+	case "home":
+		if profile.Home == "" {
+			return path
+		}
+	case "default_ai":
+		if profile.DefaultAI == "" {
+			return path
+		}
+	case "tools", "projects", "groups":
+	default:
+		return path
+	}
+	return append([]string{"profiles", c.activeProfile}, path...)
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const profileConfig = `version: 1
+home: /tmp/pancake
+default_ai: gemini
+tools: [tree]
+projects:
+  alpha:
+    remote_ssh_url: git@github.com:org/alpha.git
+profile: acme
+profiles:
+  acme:
+    home: /tmp/acme # client checkouts
+    projects:
+      portal:
+        remote_ssh_url: git@github.com:acme/portal.git
+  solo:
+    default_ai: chatgpt
+`
+
+func TestGetConfig_ActiveProfile(t *testing.T) {
+	writeConfig(t, profileConfig)
+	config, err := GetConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ActiveProfile() != "acme" || config.Home != "/tmp/acme" || config.DefaultAI != "gemini" {
+		t.Errorf("got profile %s, home %s, ai %s", config.ActiveProfile(), config.Home, config.DefaultAI)
+	}
+	if _, ok := config.Projects["portal"]; !ok || len(config.Projects) != 1 || len(config.Tools) != 0 {
+		t.Errorf("expected only the profile's projects and tools, got %v %v", config.Projects, config.Tools)
+	}
+}
+
+func TestGetConfig_ProfileOverride(t *testing.T) {
+	writeConfig(t, profileConfig)
+	t.Cleanup(func() { ProfileOverride = "" })
+
+	ProfileOverride = "solo"
+	config, err := GetConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Home != "/tmp/pancake" || config.DefaultAI != "chatgpt" || len(config.Projects) != 0 {
+		t.Errorf("got home %s, ai %s, projects %v", config.Home, config.DefaultAI, config.Projects)
+	}
+
+	ProfileOverride = DefaultProfileName
+	if config, err = GetConfig(); err != nil || len(config.Projects) != 1 || config.Projects["alpha"].RemoteSSHURL == "" {
+		t.Errorf("expected the top-level projects, got %v (%v)", config, err)
+	}
+
+	ProfileOverride = "missing"
+	if _, err := GetConfig(); err == nil || !strings.Contains(err.Error(), "default, acme, solo") {
+		t.Errorf("expected an unknown profile error listing the profiles, got %v", err)
+	}
+}
+
+func TestModifyConfig_WritesToActiveProfile(t *testing.T) {
+	configPath := writeConfig(t, profileConfig)
+	err := ModifyConfig(func(cfg *Config) error {
+		cfg.Tools = append(cfg.Tools, "jq")
+		return AddProject(cfg, "billing", Project{RemoteSSHURL: "git@github.com:acme/billing.git"})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	want := strings.Replace(profileConfig, `    home: /tmp/acme # client checkouts
+    projects:
+      portal:
+        remote_ssh_url: git@github.com:acme/portal.git
+`, `    home: /tmp/acme # client checkouts
+    projects:
+      portal:
+        remote_ssh_url: git@github.com:acme/portal.git
+      billing:
+        remote_ssh_url: git@github.com:acme/billing.git
+    tools:
+      - jq
+`, 1)
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestValidateConfig_ProfileUnknown(t *testing.T) {
+	configPath := writeConfig(t, strings.Replace(profileConfig, "profile: acme", "profile: gone", 1))
+	if _, err := GetConfig(); err == nil || !strings.Contains(err.Error(), "profile 'gone'") {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+	// Switching profile repairs the selection.
+	if err := ModifyConfig(func(cfg *Config) error { cfg.Profile = "solo"; return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.Contains(string(data), "profile: solo\n") {
+		t.Errorf("profile not switched:\n%s", data)
+	}
+}
+
+func TestValidateConfigFile_ProfilePositions(t *testing.T) {
+	configPath := writeConfig(t, strings.Replace(profileConfig, "remote_ssh_url: git@github.com:acme/portal.git", "port: abc", 1))
+	diagnostics, err := ValidateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	diagnostic := findDiagnostic(diagnostics, "'port' that is not a number")
+	if diagnostic == nil || diagnostic.Line != 14 || diagnostic.Column != 9 {
+		t.Errorf("expected the port issue at line 14, column 9, got %+v", diagnostics)
+	}
+}
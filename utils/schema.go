@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var stringListType = reflect.TypeOf(StringList{})
+
+// configFieldDescriptions documents the settings of pancake.yml in the JSON
+// Schema. Keys are dotted paths; '*' stands for any project, group or task
+// name.
+var configFieldDescriptions = map[string]string{
+	"version":                   "Format version of this file. Upgrade older files with 'pancake config migrate'.",
+	"home":                      "Absolute directory projects are cloned into, e.g. $HOME/pancake.",
+	"code_editor":               "Command that opens a project directory, e.g. 'code .' or 'idea .'.",
+	"default_ai":                "AI provider used by 'pancake chat': gemini or chatgpt. Leave empty to disable.",
+	"tools":                     "Packages installed with 'pancake tool install'.",
+	"projects":                  "Projects managed by pancake, by name.",
+	"projects.*.remote_ssh_url": "Git remote the project is cloned from.",
+	"projects.*.type":           "Project type, e.g. web.",
+	"projects.*.port":           "Port the project listens on, checked before it is started.",
+	"projects.*.run":            "Command that runs the project.",
+	"projects.*.build":          "Command that builds the project.",
+	"projects.*.depends_on":     "Projects that are started before this one.",
+	"projects.*.health":         "How 'pancake run' decides the project is up.",
+	"projects.*.health.url":     "URL that must answer with a successful HTTP status.",
+	"projects.*.health.status":  "Expected HTTP status; any 2xx or 3xx if unset.",
+	"projects.*.health.tcp":     "Port or host:port that must accept connections.",
+	"projects.*.health.command": "Command that must exit with status 0.",
+	"projects.*.health.timeout": "How long 'pancake run' waits, e.g. 90s.",
+	"projects.*.env":            "Environment variables set for the project's commands.",
+	"projects.*.env_file":       "Dotenv files loaded for the project's commands.",
+	"projects.*.commands":       "Named tasks run with 'pancake do'. Steps starting with '@' run another task.",
+	"projects.*.branch":         "Branch to clone and pull.",
+	"projects.*.depth":          "Number of commits to fetch for a shallow clone.",
+	"projects.*.submodules":     "Clone and update git submodules.",
+	"projects.*.tags":           "Tags for selecting projects with --tag.",
+	"gemini":                    "Settings for Google Gemini.",
+	"chatgpt":                   "Settings for OpenAI ChatGPT.",
+	"gemini.api_key":            "API key in plain text. Prefer api_key_env, api_key_cmd or 'pancake secret set gemini'.",
+	"gemini.api_key_env":        "Environment variable holding the API key, e.g. GEMINI_API_KEY.",
+	"gemini.api_key_cmd":        "Command that prints the API key, e.g. 'pass show gemini'.",
+	"chatgpt.api_key":           "API key in plain text. Prefer api_key_env, api_key_cmd or 'pancake secret set chatgpt'.",
+	"chatgpt.api_key_env":       "Environment variable holding the API key, e.g. OPENAI_API_KEY.",
+	"chatgpt.api_key_cmd":       "Command that prints the API key, e.g. 'pass show openai'.",
+	"env":                       "Environment variables set for every project.",
+	"env_file":                  "Dotenv files loaded for every project.",
+	"groups":                    "Named lists of projects for selecting them with --group.",
+	"include":                   "Files merged under this one: local paths or <git remote>//<path>[?ref=<branch>].",
+	"profile":                   "Active profile from 'profiles:', set with 'pancake profile use'. Empty or 'default' for the top-level settings.",
+	"profiles":                  "Named sets of projects, each with its own home, tools and AI provider.",
+	"profiles.*.home":           "Directory the profile's projects are cloned into; the top-level 'home' if unset.",
+	"profiles.*.default_ai":     "AI provider for the profile; the top-level 'default_ai' if unset.",
+	"profiles.*.tools":          "Packages installed with 'pancake tool install' while the profile is active.",
+	"profiles.*.projects":       "Projects of the profile, by name.",
+	"profiles.*.groups":         "Named lists of the profile's projects for --group.",
+}
+
+// configFieldSchemas adds to the generated schema of a field.
+var configFieldSchemas = map[string]map[string]interface{}{
+	"version":                  {"minimum": 0, "maximum": CurrentConfigVersion},
+	"default_ai":               {"enum": []string{"", "gemini", "chatgpt"}},
+	"projects.*.port":          {"type": []string{"string", "integer"}},
+	"projects.*.depth":         {"minimum": 0},
+	"projects.*.health.tcp":    {"type": []string{"string", "integer"}},
+	"projects.*.health.status": {"minimum": 100, "maximum": 599},
+}
+
+// ConfigJSONSchema returns a JSON Schema for pancake.yml, generated from
+// the Config type.
+func ConfigJSONSchema() ([]byte, error) {
+	schema := jsonSchemaFor(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "pancake.yml"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func jsonSchemaFor(t reflect.Type, path string) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema := make(map[string]interface{})
+	switch {
+	case t == stringListType:
+		schema["oneOf"] = []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		}
+	case t.Kind() == reflect.Struct:
+		properties := make(map[string]interface{})
+		for name, field := range yamlFields(t) {
+			properties[name] = jsonSchemaFor(field, joinSchemaPath(path, name))
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["additionalProperties"] = false
+	case t.Kind() == reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"] = jsonSchemaFor(t.Elem(), joinSchemaPath(path, "*"))
+	case t.Kind() == reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = jsonSchemaFor(t.Elem(), path)
+	case t.Kind() == reflect.String:
+		schema["type"] = "string"
+	case t.Kind() == reflect.Bool:
+		schema["type"] = "boolean"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema["type"] = "number"
+	default:
+		schema["type"] = "integer"
+	}
+	if description, ok := configFieldDescriptions[path]; ok {
+		schema["description"] = description
+	} else if description, ok := configFieldDescriptions[strings.TrimPrefix(path, "profiles.*.")]; ok {
+		// Settings inside a profile are documented like their top-level ones.
+		schema["description"] = description
+	}
+	for key, value := range configFieldSchemas[strings.TrimPrefix(path, "profiles.*.")] {
+		schema[key] = value
+	}
+	return schema
+}
+
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// yamlFields returns the types of the fields of struct type t by YAML key.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// configNodeIssue is a ConfigIssue found at a node of the YAML document.
+type configNodeIssue struct {
+	node *yamlv3.Node
+	ConfigIssue
+}
+
+// checkConfigNode reports keys that type t has no field for and values of
+// the wrong kind in node and everything below it.
+func checkConfigNode(node *yamlv3.Node, t reflect.Type, path []string, issues *[]configNodeIssue) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+	if node.ShortTag() == "!!null" {
+		return
+	}
+	wrongType := func(expected string) {
+		*issues = append(*issues, configNodeIssue{node, ConfigIssue{
+			Path:    path,
+			Message: fmt.Sprintf(ConfigErrFieldType, strings.Join(path, "."), expected, describeYAMLNode(node)),
+		}})
+	}
+
+	switch {
+	case t == stringListType:
+		if node.Kind == yamlv3.SequenceNode {
+			checkConfigNode(node, reflect.TypeOf([]string{}), path, issues)
+		} else if node.Kind != yamlv3.ScalarNode {
+			wrongType("a string or a list of strings")
+		}
+	case t.Kind() == reflect.Struct:
+		if node.Kind != yamlv3.MappingNode {
+			wrongType("a mapping of settings")
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, known := fields[key.Value]
+			if !known {
+				*issues = append(*issues, configNodeIssue{key, ConfigIssue{
+					Path:    childPath(path, key.Value),
+					Message: fmt.Sprintf(ConfigWarnFieldUnknown, strings.Join(childPath(path, key.Value), "."), suggestField(key.Value, fields)),
+					Warning: true,
+				}})
+				continue
+			}
+			checkConfigNode(value, fieldType, childPath(path, key.Value), issues)
+		}
+	case t.Kind() == reflect.Map:
+		if node.Kind != yamlv3.MappingNode {
+			wrongType("a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkConfigNode(node.Content[i+1], t.Elem(), childPath(path, node.Content[i].Value), issues)
+		}
+	case t.Kind() == reflect.Slice:
+		if node.Kind != yamlv3.SequenceNode {
+			wrongType("a list")
+			return
+		}
+		for i, item := range node.Content {
+			checkConfigNode(item, t.Elem(), childPath(path, strconv.Itoa(i)), issues)
+		}
+	case node.Kind != yamlv3.ScalarNode:
+		wrongType("a single value")
+	case t.Kind() == reflect.Bool:
+		if !yamlBool(node) {
+			wrongType("true or false")
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		if node.ShortTag() != "!!int" && node.ShortTag() != "!!float" {
+			wrongType("a number")
+		}
+	case t.Kind() != reflect.String:
+		if node.ShortTag() != "!!int" {
+			wrongType("a whole number")
+		}
+	}
+}
+
+// yamlBool reports whether node decodes to a boolean. pancake.yml is decoded
+// with yaml.v2, which also takes YAML 1.1 spellings such as yes, no, on and
+// off, so those are accepted too.
+func yamlBool(node *yamlv3.Node) bool {
+	if node.ShortTag() == "!!bool" {
+		return true
+	}
+	if node.Style != 0 {
+		return false // quoted, tagged or a block scalar
+	}
+	var value bool
+	return yaml.Unmarshal([]byte(node.Value), &value) == nil
+}
+
+func childPath(path []string, key string) []string {
+	return append(append([]string(nil), path...), key)
+}
+
+func describeYAMLNode(node *yamlv3.Node) string {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		return "a mapping"
+	case yamlv3.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("'%s'", node.Value)
+}
+
+// suggestField returns " (did you mean 'x'?)" for the known field closest
+// to a misspelt key, or "" if none is close.
+func suggestField(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		if distance := editDistance(key, name); distance < bestDistance || distance == bestDistance && name < best {
+			best, bestDistance = name, distance
+		}
+	}
+	if best == "" || bestDistance >= len(key) {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean '%s'?)", best)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
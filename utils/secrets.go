@@ -0,0 +1,322 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	secretsFileName    = "secrets.enc"
+	secretsKeyFileName = "secrets.key"
+)
+
+// ResolveAPIKey returns the API key of an AI provider. In order, it is read
+// from the environment variable named by apiKeyEnv, from the output of
+// apiKeyCmd, from apiKey itself, or from the secret called secretName in
+// the local secrets file. It returns "" if none of them is set.
+func ResolveAPIKey(secretName, apiKey, apiKeyEnv, apiKeyCmd string) (string, error) {
+	if apiKeyEnv != "" {
+		value := strings.TrimSpace(os.Getenv(apiKeyEnv))
+		if value == "" {
+			return "", fmt.Errorf("'%s.api_key_env' names the environment variable %s, which is not set", secretName, apiKeyEnv)
+		}
+		return value, nil
+	}
+	if apiKeyCmd != "" {
+		command := buildShellCommand(apiKeyCmd)
+		command.Stderr = os.Stderr
+		out, err := command.Output()
+		if err != nil {
+			return "", fmt.Errorf("'%s.api_key_cmd' (%s) failed: %w", secretName, apiKeyCmd, err)
+		}
+		value := strings.TrimSpace(string(out))
+		if value == "" {
+			return "", fmt.Errorf("'%s.api_key_cmd' (%s) printed nothing", secretName, apiKeyCmd)
+		}
+		return value, nil
+	}
+	if apiKey != "" {
+		return apiKey, nil
+	}
+	secrets, err := LoadSecrets()
+	if err != nil {
+		return "", err
+	}
+	return secrets[secretName], nil
+}
+
+// SecretsPath returns the encrypted secrets file, kept in the user's config
+// directory next to the key that encrypts it.
+func SecretsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not resolve the user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "pancake", secretsFileName), nil
+}
+
+// LoadSecrets decrypts the secrets file. A missing file holds no secrets.
+func LoadSecrets() (map[string]string, error) {
+	secretsPath, err := SecretsPath()
+	if err != nil {
+		return nil, err
+	}
+	return readSecrets(secretsPath)
+}
+
+// SetSecret stores value under name in the secrets file, creating the file
+// and its key on first use.
+func SetSecret(name, value string) error {
+	return updateSecrets(func(secrets map[string]string) { secrets[name] = value })
+}
+
+// DeleteSecret removes name from the secrets file and reports whether it
+// was there.
+func DeleteSecret(name string) (bool, error) {
+	existed := false
+	err := updateSecrets(func(secrets map[string]string) {
+		_, existed = secrets[name]
+		delete(secrets, name)
+	})
+	return existed, err
+}
+
+func updateSecrets(update func(map[string]string)) error {
+	secretsPath, err := SecretsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(secretsPath), 0700); err != nil {
+		return fmt.Errorf("could not create %s: %w", filepath.Dir(secretsPath), err)
+	}
+	unlock, err := LockFile(secretsPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, err := readSecrets(secretsPath)
+	if err != nil {
+		return err
+	}
+	update(secrets)
+	key, err := secretsKey(filepath.Dir(secretsPath), true)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealSecrets(key, plaintext)
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(secretsPath, sealed, 0600); err != nil {
+		return fmt.Errorf("could not write the secrets file at %s: %w", secretsPath, err)
+	}
+	return nil
+}
+
+func readSecrets(secretsPath string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	sealed, err := os.ReadFile(secretsPath)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read the secrets file at %s: %w", secretsPath, err)
+	}
+	key, err := secretsKey(filepath.Dir(secretsPath), false)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openSecrets(key, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the secrets file at %s with %s: %w", secretsPath, filepath.Join(filepath.Dir(secretsPath), secretsKeyFileName), err)
+	}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("the secrets file at %s is damaged: %w", secretsPath, err)
+	}
+	return secrets, nil
+}
+
+// secretsKey reads the 256-bit key in dir, generating it if create is set
+// and there is none. The key file is readable by the user only.
+func secretsKey(dir string, create bool) ([]byte, error) {
+	keyPath := filepath.Join(dir, secretsKeyFileName)
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("the secrets key at %s is not 32 bytes long", keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("could not read the secrets key at %s: %w", keyPath, err)
+	}
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := WriteFileAtomic(keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("could not write the secrets key at %s: %w", keyPath, err)
+	}
+	return key, nil
+}
+
+// sealSecrets encrypts plaintext with AES-256-GCM, prefixing the nonce.
+func sealSecrets(key, plaintext []byte) ([]byte, error) {
+	aead, err := newSecretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openSecrets(key, sealed []byte) ([]byte, error) {
+	aead, err := newSecretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("the file is truncated")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+func newSecretsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sensitiveEnvName matches environment variable names that usually hold
+// credentials.
+var sensitiveEnvName = regexp.MustCompile(`(?i)(secret|token|passw(or)?d|api_?key|private_?key|credential|auth)`)
+
+// sensitiveQueryName matches URL query parameters that usually hold
+// credentials, such as the '?key=' of the Gemini API.
+var sensitiveQueryName = regexp.MustCompile(`(?i)(^key$|^sig$|signature|secret|token|passw(or)?d|api_?key|credential|auth)`)
+
+// StripSecrets returns the config file data with its secrets blanked: every
+// 'api_key', env values whose names look like credentials, and the
+// credentials in URLs, that is passwords, the user of http(s) URLs, which is
+// usually a token, and query parameters such as 'key'. URLs are looked for
+// in settings whose names end in 'url' and in every env value. Comments and
+// layout are kept. The dotted paths of the blanked values are returned too.
+func StripSecrets(data []byte) ([]byte, []string, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return data, nil, nil
+	}
+	canonical, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var stripped []string
+	stripSecretNodes(doc.Content[0], nil, false, &stripped)
+	if len(stripped) == 0 {
+		return data, nil, nil
+	}
+	shared, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keepLayout(data, canonical, shared), stripped, nil
+}
+
+func stripSecretNodes(node *yamlv3.Node, path []string, inEnv bool, stripped *[]string) {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			valuePath := childPath(path, key)
+			if value.Kind != yamlv3.ScalarNode {
+				stripSecretNodes(value, valuePath, strings.EqualFold(key, "env"), stripped)
+				continue
+			}
+			switch {
+			case strings.EqualFold(key, "api_key") || inEnv && sensitiveEnvName.MatchString(key):
+				if value.Value == "" || strings.HasPrefix(value.Value, "${") {
+					continue // empty, or taken from the environment
+				}
+				value.Value, value.Tag, value.Style = "", "!!str", yamlv3.DoubleQuotedStyle
+			case inEnv || strings.HasSuffix(strings.ToLower(key), "url") || strings.EqualFold(key, "include"):
+				shared, changed := stripURLCredentials(value.Value)
+				if !changed {
+					continue
+				}
+				value.Value = shared
+			default:
+				continue
+			}
+			*stripped = append(*stripped, strings.Join(valuePath, "."))
+		}
+	case yamlv3.SequenceNode:
+		inInclude := len(path) > 0 && strings.EqualFold(path[len(path)-1], "include")
+		for i, item := range node.Content {
+			if inInclude && item.Kind == yamlv3.ScalarNode {
+				// Private includes are fetched from URLs that may carry a token.
+				if shared, changed := stripURLCredentials(item.Value); changed {
+					item.Value = shared
+					*stripped = append(*stripped, strings.Join(childPath(path, strconv.Itoa(i)), "."))
+				}
+				continue
+			}
+			stripSecretNodes(item, path, false, stripped)
+		}
+	}
+}
+
+// stripURLCredentials removes the password, the user of an http(s) URL and
+// the values of credential-like query parameters from rawURL. It reports
+// whether anything was removed; values that are not URLs are left alone.
+func stripURLCredentials(rawURL string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return rawURL, false
+	}
+	changed := false
+	if parsed.User != nil {
+		_, hasPassword := parsed.User.Password()
+		switch {
+		case parsed.Scheme == "http" || parsed.Scheme == "https":
+			parsed.User, changed = nil, true
+		case hasPassword:
+			parsed.User, changed = url.User(parsed.User.Username()), true
+		}
+	}
+	if parsed.RawQuery != "" {
+		// Rewritten by hand to keep the order of the parameters.
+		parameters := strings.Split(parsed.RawQuery, "&")
+		for i, parameter := range parameters {
+			name, value, _ := strings.Cut(parameter, "=")
+			if value != "" && sensitiveQueryName.MatchString(name) {
+				parameters[i], changed = name+"=", true
+			}
+		}
+		parsed.RawQuery = strings.Join(parameters, "&")
+	}
+	return parsed.String(), changed
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SelectProjects returns the projects that belong to any of groups or carry
+// any of tags, sorted by name. Every group must exist and every tag must
+// match at least one project.
+func SelectProjects(config *Config, groups, tags []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, group := range groups {
+		members, ok := config.Groups[group]
+		if !ok {
+			return nil, fmt.Errorf("group '%s' is not defined under 'groups:' in pancake.yml", group)
+		}
+		for _, projectName := range members {
+			selected[projectName] = true
+		}
+	}
+	for _, tag := range tags {
+		matched := false
+		for projectName, project := range config.Projects {
+			for _, projectTag := range project.Tags {
+				if projectTag == tag {
+					selected[projectName] = true
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no project has the tag '%s'", tag)
+		}
+	}
+	names := make([]string, 0, len(selected))
+	for projectName := range selected {
+		names = append(names, projectName)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SortByDependencies orders names so that each project comes after the
+// selected projects it depends on, without adding unselected dependencies.
+func SortByDependencies(projects map[string]Project, names []string) []string {
+	order, err := DependencyOrder(projects, names...)
+	if err != nil {
+		sorted := append([]string(nil), names...)
+		sort.Strings(sorted)
+		return sorted
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	filtered := make([]string, 0, len(names))
+	for _, name := range order {
+		if wanted[name] {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
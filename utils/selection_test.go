@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func selectionConfig() *Config {
+	return &Config{
+		Groups: map[string][]string{"backend": {"api", "worker"}},
+		Projects: map[string]Project{
+			"api":    {Tags: []string{"go"}},
+			"worker": {Tags: []string{"go"}, DependsOn: []string{"api"}},
+			"web":    {Tags: []string{"node"}, DependsOn: []string{"api"}},
+			"infra":  {},
+		},
+	}
+}
+
+func TestSelectProjects(t *testing.T) {
+	config := selectionConfig()
+	names, err := SelectProjects(config, []string{"backend"}, []string{"node"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"api", "web", "worker"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+	if _, err := SelectProjects(config, []string{"frontend"}, nil); err == nil {
+		t.Fatal("expected an error for an unknown group")
+	}
+	if _, err := SelectProjects(config, nil, []string{"rust"}); err == nil {
+		t.Fatal("expected an error for a tag no project has")
+	}
+}
+
+func TestSortByDependencies(t *testing.T) {
+	config := selectionConfig()
+	if order := SortByDependencies(config.Projects, []string{"worker", "web", "api"}); !reflect.DeepEqual(order, []string{"api", "web", "worker"}) {
+		t.Fatalf("order = %v", order)
+	}
+	if order := SortByDependencies(config.Projects, []string{"worker", "infra"}); !reflect.DeepEqual(order, []string{"infra", "worker"}) {
+		t.Fatalf("unselected dependencies should not be added, got %v", order)
+	}
+}
@@ -3,9 +3,12 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -13,6 +16,8 @@ import (
 
 type GeminiConfig struct {
 	APIKey      string  `yaml:"api_key"`
+	APIKeyEnv   string  `yaml:"api_key_env,omitempty"`
+	APIKeyCmd   string  `yaml:"api_key_cmd,omitempty"`
 	Temperature float32 `yaml:"temperature"`
 	URL         string  `yaml:"url"`
 	Context     string  `yaml:"context"`
@@ -20,6 +25,8 @@ type GeminiConfig struct {
 
 type ChatGPTConfig struct {
 	APIKey      string  `yaml:"api_key"`
+	APIKeyEnv   string  `yaml:"api_key_env,omitempty"`
+	APIKeyCmd   string  `yaml:"api_key_cmd,omitempty"`
 	Temperature float32 `yaml:"temperature"`
 	URL         string  `yaml:"url"`
 	Model       string  `yaml:"model"`
@@ -27,29 +34,131 @@ type ChatGPTConfig struct {
 }
 
 type Config struct {
-	Home       string             `yaml:"home"`
-	CodeEditor string             `yaml:"code_editor"`
-	DefaultAI  string             `yaml:"default_ai"`
-	Tools      []string           `yaml:"tools"`
-	Projects   map[string]Project `yaml:"projects"`
-	Gemini     GeminiConfig       `yaml:"gemini"`
-	ChatGPT    ChatGPTConfig      `yaml:"chatgpt"`
+	Version    int                 `yaml:"version,omitempty"`
+	Home       string              `yaml:"home"`
+	CodeEditor string              `yaml:"code_editor"`
+	DefaultAI  string              `yaml:"default_ai"`
+	Tools      []string            `yaml:"tools"`
+	Projects   map[string]Project  `yaml:"projects"`
+	Gemini     GeminiConfig        `yaml:"gemini"`
+	ChatGPT    ChatGPTConfig       `yaml:"chatgpt"`
+	Env        map[string]string   `yaml:"env,omitempty"`
+	EnvFile    StringList          `yaml:"env_file,omitempty"`
+	Groups     map[string][]string `yaml:"groups,omitempty"`
+	Include    StringList          `yaml:"include,omitempty"`
+	Profile    string              `yaml:"profile,omitempty"`
+	Profiles   map[string]Profile  `yaml:"profiles,omitempty"`
+
+	// activeProfile is the profile whose settings replaced the top-level
+	// ones, which are kept in base.
+	activeProfile string
+	base          *Profile
+
+	// loaded is a copy of the config as GetConfig read it, against which
+	// UpdateConfig finds the changes to write.
+	loaded *Config
 }
 
 type Project struct {
-	RemoteSSHURL string `yaml:"remote_ssh_url"`
-	Type         string `yaml:"type,omitempty"`
-	Port         string `yaml:"port,omitempty"`
-	Run          string `yaml:"run,omitempty"`
-	Build        string `yaml:"build,omitempty"`
+	RemoteSSHURL string                `yaml:"remote_ssh_url"`
+	Type         string                `yaml:"type,omitempty"`
+	Port         string                `yaml:"port,omitempty"`
+	Run          string                `yaml:"run,omitempty"`
+	Build        string                `yaml:"build,omitempty"`
+	DependsOn    []string              `yaml:"depends_on,omitempty"`
+	Health       *HealthCheck          `yaml:"health,omitempty"`
+	Env          map[string]string     `yaml:"env,omitempty"`
+	EnvFile      StringList            `yaml:"env_file,omitempty"`
+	Commands     map[string]StringList `yaml:"commands,omitempty"`
+	Branch       string                `yaml:"branch,omitempty"`
+	Depth        int                   `yaml:"depth,omitempty"`
+	Submodules   bool                  `yaml:"submodules,omitempty"`
+	Tags         []string              `yaml:"tags,omitempty"`
+}
+
+// StringList is a YAML field that accepts either a single string or a list.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
 }
 
+// ConfigPathOverride is the config file given with the --config flag.
+var ConfigPathOverride string
+
+// ConfigPath returns the config file pancake uses; see FindConfigPath.
 func ConfigPath() (string, error) {
+	configPath, _, err := FindConfigPath()
+	return configPath, err
+}
+
+// FindConfigPath returns the config file pancake uses and where that choice
+// came from. In order, it is the file given with --config, the file named by
+// $PANCAKE_CONFIG, a .pancake.yml in the working directory or one of its
+// parents, $XDG_CONFIG_HOME/pancake/pancake.yml if it exists, and otherwise
+// $HOME/pancake.yml. The first two need not exist yet, so 'pancake init'
+// can create them.
+func FindConfigPath() (string, string, error) {
+	if ConfigPathOverride != "" {
+		configPath, err := absoluteConfigPath(ConfigPathOverride)
+		return configPath, "--config flag", err
+	}
+	if value := os.Getenv(ConfigPathEnv); value != "" {
+		configPath, err := absoluteConfigPath(value)
+		return configPath, ConfigPathEnv, err
+	}
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			candidate := filepath.Join(dir, LocalConfigFileName)
+			if stat, err := os.Stat(candidate); err == nil && !stat.IsDir() {
+				return candidate, "project-local " + LocalConfigFileName, nil
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return "", fmt.Errorf("could not resolve user home directory: %w", err)
+		return "", "", fmt.Errorf("could not resolve user home directory: %w", err)
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(configHome) {
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	candidate := filepath.Join(configHome, "pancake", ConfigFileName)
+	if stat, err := os.Stat(candidate); err == nil && !stat.IsDir() {
+		return candidate, "XDG_CONFIG_HOME", nil
+	}
+	return filepath.Join(homeDir, ConfigFileName), "default", nil
+}
+
+func absoluteConfigPath(path string) (string, error) {
+	expanded, err := ExpandHomePath(path)
+	if err != nil {
+		return "", err
+	}
+	if expanded == "~" || strings.HasPrefix(expanded, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not resolve user home directory: %w", err)
+		}
+		expanded = filepath.Join(homeDir, strings.TrimPrefix(expanded[1:], "/"))
 	}
-	return filepath.Join(homeDir, ConfigFileName), nil
+	return filepath.Abs(expanded)
 }
 
 func ExpandHomePath(path string) (string, error) {
@@ -83,12 +192,80 @@ func replaceCaseInsensitive(input, target, replacement string) string {
 	return builder.String()
 }
 
+// ConfigWarnings is where commands that change pancake.yml report that
+// they upgraded it from an older format. nil keeps them quiet.
+var ConfigWarnings io.Writer = os.Stderr
+
+// GetConfig loads pancake.yml. A file in an older format is upgraded in
+// memory only and quietly: GetConfig never writes. Commands that change the
+// file upgrade it on disk through ModifyConfig, and 'pancake config
+// validate' points to 'pancake config migrate'.
 func GetConfig() (*Config, error) {
 	configPath, err := ConfigPath()
 	if err != nil {
 		return nil, err
 	}
 
+	file, err := readConfigData(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	migration, err := upgradeConfigData(configPath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := decodeConfig(configPath, migration.Migrated)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	if config.loaded, err = config.clone(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// clone returns a deep copy of c with the same profile applied.
+func (c *Config) clone() (*Config, error) {
+	data, err := yaml.Marshal(c.fileView())
+	if err != nil {
+		return nil, err
+	}
+	var clone Config
+	if err := yaml.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	if err := clone.applyProfile(); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// readConfigFile reads and decodes pancake.yml, expanding 'home'. A file in
+// an older format is upgraded in memory; the returned migration holds the
+// raw file as well, so that writes can keep its formatting.
+func readConfigFile(configPath string) (*ConfigMigration, *Config, error) {
+	file, err := readConfigData(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	migration, err := upgradeConfigData(configPath, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := decodeConfig(configPath, migration.Migrated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migration, config, nil
+}
+
+func readConfigData(configPath string) ([]byte, error) {
 	file, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -96,69 +273,291 @@ func GetConfig() (*Config, error) {
 		}
 		return nil, errors.New(fmt.Sprintf(ConfigErrReadFailed, configPath, configPath))
 	}
+	return file, nil
+}
 
+func decodeConfig(configPath string, file []byte) (*Config, error) {
 	var config Config
 	if err := yaml.Unmarshal(file, &config); err != nil {
 		return nil, errors.New(fmt.Sprintf(ConfigErrParseFailed, err.Error(), configPath))
 	}
 
+	if len(config.Include) > 0 {
+		resolved, err := ResolveConfigIncludes(configPath, file, false)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(ConfigErrIncludeFailed, err.Error()))
+		}
+		merged, err := resolved.Encode(false)
+		if err != nil {
+			return nil, err
+		}
+		config = Config{}
+		if err := yaml.Unmarshal(merged, &config); err != nil {
+			return nil, errors.New(fmt.Sprintf(ConfigErrIncludeFailed, err.Error()))
+		}
+	}
+
+	if err := config.applyProfile(); err != nil {
+		return nil, err
+	}
 	expanded, err := ExpandHomePath(config.Home)
 	if err != nil {
 		return nil, err
 	}
 	config.Home = expanded
-
-	if err := ValidateConfig(&config); err != nil {
-		return nil, err
-	}
-
 	return &config, nil
 }
 
+// ConfigIssue is a problem found in a loaded configuration. Path names the
+// offending setting as YAML keys and list indexes, e.g. projects, api, port.
+// Warnings do not stop pancake from loading the file.
+type ConfigIssue struct {
+	Path    []string
+	Message string
+	Warning bool
+}
+
 func ValidateConfig(config *Config) error {
 	var issues []string
+	for _, issue := range configIssues(config) {
+		if !issue.Warning {
+			issues = append(issues, issue.Message)
+		}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(append([]string{"pancake.yml has issues:"}, issues...), "\n - "))
+}
+
+func configIssues(config *Config) []ConfigIssue {
+	var issues []ConfigIssue
+	report := func(message string, path ...string) {
+		issues = append(issues, ConfigIssue{Path: path, Message: message})
+	}
 
 	if strings.TrimSpace(config.Home) == "" {
-		issues = append(issues, fmt.Sprintf(ConfigErrHomeEmpty))
+		report(fmt.Sprintf(ConfigErrHomeEmpty), "home")
 	} else if !filepath.IsAbs(config.Home) {
-		issues = append(issues, fmt.Sprintf(ConfigErrHomeRelative, config.Home))
+		report(fmt.Sprintf(ConfigErrHomeRelative, config.Home), "home")
 	}
 
 	switch config.DefaultAI {
 	case "", "gemini", "chatgpt":
 	default:
-		issues = append(issues, fmt.Sprintf(ConfigErrDefaultAIInvalid, config.DefaultAI))
+		report(fmt.Sprintf(ConfigErrDefaultAIInvalid, config.DefaultAI), "default_ai")
 	}
 
-	for projectName := range config.Projects {
-		if strings.ContainsAny(projectName, `/\`) {
-			issues = append(issues, fmt.Sprintf(ConfigErrProjectNameInvalid, projectName))
+	if config.Profile != "" && config.Profile != DefaultProfileName {
+		if _, exists := config.Profiles[config.Profile]; !exists {
+			report(fmt.Sprintf(ConfigErrProfileUnknown, config.Profile, strings.Join(ProfileNames(config), ", ")), "profile")
+		}
+	}
+	if _, exists := config.Profiles[DefaultProfileName]; exists {
+		report(fmt.Sprintf(ConfigErrProfileNameReserved, DefaultProfileName), "profiles", DefaultProfileName)
+	}
+
+	portOwners := make(map[int]string)
+	for _, projectName := range sortedKeys(config.Projects) {
+		if !validProjectName(projectName) {
+			report(fmt.Sprintf(ConfigErrProjectNameInvalid, projectName), "projects", projectName)
 			continue
 		}
 		project := config.Projects[projectName]
 		if strings.TrimSpace(project.RemoteSSHURL) == "" {
-			issues = append(issues, fmt.Sprintf(ConfigErrProjectRemoteMissing, projectName, projectName))
+			report(fmt.Sprintf(ConfigErrProjectRemoteMissing, projectName, projectName), "projects", projectName)
+		}
+		if project.Port != "" {
+			if port, err := ParsePort(project.Port); err != nil {
+				issues = append(issues, ConfigIssue{
+					Path:    []string{"projects", projectName, "port"},
+					Message: fmt.Sprintf(ConfigWarnProjectPortInvalid, projectName, project.Port),
+					Warning: true,
+				})
+			} else if owner, taken := portOwners[port]; taken {
+				issues = append(issues, ConfigIssue{
+					Path:    []string{"projects", projectName, "port"},
+					Message: fmt.Sprintf(ConfigWarnProjectPortDuplicate, projectName, owner, port),
+					Warning: true,
+				})
+			} else {
+				portOwners[port] = projectName
+			}
+		}
+		if project.Depth < 0 {
+			report(fmt.Sprintf(ConfigErrProjectDepthInvalid, projectName, project.Depth), "projects", projectName, "depth")
+		}
+		if project.Branch != "" && !validBranchName(project.Branch) {
+			report(fmt.Sprintf(ConfigErrProjectBranchInvalid, projectName, project.Branch), "projects", projectName, "branch")
+		}
+		if project.Health != nil {
+			if problem := project.Health.validate(); problem != "" {
+				report(fmt.Sprintf(ConfigErrProjectHealthInvalid, projectName, problem), "projects", projectName, "health")
+			}
+		}
+		for _, task := range TaskNames(project) {
+			if _, err := ExpandTask(project, task); err != nil {
+				report(fmt.Sprintf(ConfigErrProjectTaskInvalid, projectName, err), "projects", projectName, "commands", task)
+			}
+		}
+		for i, dependency := range project.DependsOn {
+			if _, exists := config.Projects[dependency]; !exists {
+				report(fmt.Sprintf(ConfigErrProjectDependencyUnknown, projectName, dependency), "projects", projectName, "depends_on", strconv.Itoa(i))
+			}
 		}
 	}
+	for _, group := range sortedKeys(config.Groups) {
+		for i, projectName := range config.Groups[group] {
+			if _, exists := config.Projects[projectName]; !exists {
+				report(fmt.Sprintf(ConfigErrGroupMemberUnknown, group, projectName), "groups", group, strconv.Itoa(i))
+			}
+		}
+	}
+	if cycle := DependencyCycle(config.Projects); cycle != nil {
+		report(fmt.Sprintf(ConfigErrProjectDependencyCycle, strings.Join(cycle, " -> ")), "projects", cycle[0], "depends_on")
+	}
+	return issues
+}
 
-	if len(issues) == 0 {
-		return nil
+// validProjectName reports whether projectName can name a directory under
+// home: it must be a single path element that is not hidden and cannot be
+// taken for a flag.
+func validProjectName(projectName string) bool {
+	return projectName != "" && !strings.ContainsAny(projectName, `/\`) &&
+		!strings.HasPrefix(projectName, ".") && !strings.HasPrefix(projectName, "-")
+}
+
+// validBranchName applies the rules of 'git check-ref-format --branch'
+// without running git, which sync does before using the branch.
+func validBranchName(branch string) bool {
+	if branch == "@" || strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") ||
+		strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".") ||
+		strings.Contains(branch, "..") || strings.Contains(branch, "@{") || strings.Contains(branch, "//") {
+		return false
 	}
-	return errors.New(strings.Join(append([]string{"pancake.yml has issues:"}, issues...), "\n - "))
+	for _, r := range branch {
+		if r < ' ' || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return false
+		}
+	}
+	for _, component := range strings.Split(branch, "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
+// UpdateConfig writes the changes made to config since GetConfig loaded it
+// to pancake.yml. It is ModifyConfig with those changes as the modification,
+// so settings that other pancake processes changed in the meantime are kept.
+// A config that did not come from GetConfig is written as a whole over the
+// settings it sets. Prefer ModifyConfig in new code.
 func UpdateConfig(config *Config) error {
 	configPath, err := ConfigPath()
 	if err != nil {
 		return err
 	}
+	if !CheckExists(configPath) {
+		unlock, err := LockFile(configPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return writeConfigFile(configPath, &ConfigMigration{}, &Config{}, config)
+	}
+	loaded := config.loaded
+	if loaded == nil {
+		loaded = &Config{}
+	}
+	return ModifyConfig(func(current *Config) error {
+		return applyConfigChanges(current, loaded, config)
+	})
+}
+
+// applyConfigChanges applies the difference between loaded and changed, two
+// states of one config, to current.
+func applyConfigChanges(current, loaded, changed *Config) error {
+	original, err := yaml.Marshal(current.fileView())
+	if err != nil {
+		return err
+	}
+	data, err := EditYAML(original, loaded.fileView(), changed.fileView())
+	if err != nil {
+		return err
+	}
+	var merged Config
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		return err
+	}
+	if err := merged.applyProfile(); err != nil {
+		return err
+	}
+	*current = merged
+	return nil
+}
+
+// ModifyConfig applies modify to the current pancake.yml while holding its
+// lock, validates the result and writes it back. The file is read again
+// after the lock is taken, so changes made by other pancake processes in
+// the meantime are kept rather than overwritten.
+func ModifyConfig(modify func(*Config) error) error {
+	configPath, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	unlock, err := LockFile(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	data, err := yaml.Marshal(config)
+	migration, onDisk, err := readConfigFile(configPath)
 	if err != nil {
-		return fmt.Errorf("could not encode pancake.yml: %w", err)
+		return err
+	}
+	config, err := decodeConfig(configPath, migration.Migrated)
+	if err != nil {
+		return err
+	}
+	if err := modify(config); err != nil {
+		return err
+	}
+	if err := ValidateConfig(config); err != nil {
+		return err
 	}
+	return writeConfigFile(configPath, migration, onDisk, config)
+}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+// writeConfigFile writes config over the file migration was read from. If
+// the file had to be upgraded, the original is backed up first, as 'pancake
+// config migrate' does.
+func writeConfigFile(configPath string, migration *ConfigMigration, onDisk, config *Config) error {
+	data, err := EditYAML(migration.Migrated, onDisk.fileView(), config.fileView())
+	if err != nil {
+		return fmt.Errorf("could not encode pancake.yml: %w", err)
+	}
+	if err := checkIncludedRemovals(configPath, data, onDisk, config); err != nil {
+		return err
+	}
+	if len(migration.Applied) > 0 {
+		if err := backupConfigFile(configPath, migration); err != nil {
+			return err
+		}
+		if ConfigWarnings != nil {
+			fmt.Fprintf(ConfigWarnings, "Upgraded pancake.yml from version %d to %d (backup: %s).\n", migration.FromVersion, CurrentConfigVersion, migration.Backup)
+		}
+	}
+	if err := WriteFileAtomic(configPath, data, 0644); err != nil {
 		return fmt.Errorf("could not write pancake.yml at %s: %w", configPath, err)
 	}
 	return nil
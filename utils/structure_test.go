@@ -19,6 +19,8 @@ func writeConfig(t *testing.T, contents string) string {
 	if runtime.GOOS == "windows" {
 		t.Setenv("USERPROFILE", home)
 	}
+	t.Setenv(ConfigPathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
 	return configPath
 }
 
@@ -97,6 +99,29 @@ func TestGetConfig_ExpandsEnvVars(t *testing.T) {
 	}
 }
 
+func TestGetConfig_EnvFileScalarOrList(t *testing.T) {
+	writeConfig(t, `home: $HOME/pancake
+env_file: shared.env
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    env_file: [.env, .env.local]
+    env:
+      PORT: "8080"
+`)
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.EnvFile) != 1 || cfg.EnvFile[0] != "shared.env" {
+		t.Fatalf("global env_file = %v", cfg.EnvFile)
+	}
+	api := cfg.Projects["api"]
+	if len(api.EnvFile) != 2 || api.Env["PORT"] != "8080" {
+		t.Fatalf("project env not parsed: %+v", api)
+	}
+}
+
 func TestGetConfig_ParseError(t *testing.T) {
 	writeConfig(t, "home: $HOME/pancake\ncode_editor: echo\n  bad: : :\n:invalid")
 	_, err := GetConfig()
@@ -158,6 +183,18 @@ func TestValidateConfig_ProjectNameInvalid(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_ProjectNameUnsafe(t *testing.T) {
+	for _, name := range []string{"", ".", "..", ".hidden", "-rf"} {
+		cfg := &Config{
+			Home:     "/abs/path",
+			Projects: map[string]Project{name: {RemoteSSHURL: "git@github.com:org/repo.git"}},
+		}
+		if err := ValidateConfig(cfg); err == nil {
+			t.Errorf("expected an error for project name %q", name)
+		}
+	}
+}
+
 func TestValidateConfig_ProjectRemoteMissing(t *testing.T) {
 	cfg := &Config{
 		Home:      "/abs/path",
@@ -175,6 +212,97 @@ func TestValidateConfig_ProjectRemoteMissing(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_ProjectPortInvalidIsAWarning(t *testing.T) {
+	cfg := &Config{
+		Home:      "/abs/path",
+		DefaultAI: "gemini",
+		Projects: map[string]Project{
+			"demo": {RemoteSSHURL: "git@github.com:org/repo.git", Port: "${PORT}"},
+		},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("a port that is not a number should not stop pancake from loading: %v", err)
+	}
+	issues := configIssues(cfg)
+	if len(issues) != 1 || !issues[0].Warning || !strings.Contains(issues[0].Message, "'port' that is not a number") {
+		t.Fatalf("expected a warning, got %+v", issues)
+	}
+}
+
+func TestValidateConfig_DependencyUnknown(t *testing.T) {
+	cfg := &Config{
+		Home: "/abs/path",
+		Projects: map[string]Project{
+			"web": {RemoteSSHURL: "git@github.com:org/web.git", DependsOn: []string{"api"}},
+		},
+	}
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "depends on unknown project 'api'") {
+		t.Fatalf("expected unknown dependency error, got %v", err)
+	}
+}
+
+func TestValidateConfig_DependencyCycle(t *testing.T) {
+	cfg := &Config{
+		Home: "/abs/path",
+		Projects: map[string]Project{
+			"api": {RemoteSSHURL: "git@github.com:org/api.git", DependsOn: []string{"web"}},
+			"web": {RemoteSSHURL: "git@github.com:org/web.git", DependsOn: []string{"api"}},
+			"db":  {RemoteSSHURL: "git@github.com:org/db.git", DependsOn: []string{"db"}},
+		},
+	}
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "cycle: api -> web -> api") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestValidateConfig_HealthInvalid(t *testing.T) {
+	cfg := &Config{
+		Home: "/abs/path",
+		Projects: map[string]Project{
+			"empty":   {RemoteSSHURL: "git@github.com:org/a.git", Health: &HealthCheck{}},
+			"timeout": {RemoteSSHURL: "git@github.com:org/b.git", Health: &HealthCheck{TCP: "3000", Timeout: "soon"}},
+		},
+	}
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("expected error for invalid health sections")
+	}
+	for _, want := range []string{"set at least one of 'url', 'tcp' or 'command'", "'timeout' must be a duration"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in: %v", want, err)
+		}
+	}
+}
+
+func TestValidateConfig_TaskInvalid(t *testing.T) {
+	cfg := &Config{
+		Home: "/abs/path",
+		Projects: map[string]Project{
+			"api": {RemoteSSHURL: "git@github.com:org/api.git", Commands: map[string]StringList{"ci": {"@lint"}}},
+		},
+	}
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "unknown task 'lint'") {
+		t.Fatalf("expected unknown task error, got %v", err)
+	}
+}
+
+func TestValidateConfig_GroupMemberUnknown(t *testing.T) {
+	cfg := &Config{
+		Home:   "/abs/path",
+		Groups: map[string][]string{"backend": {"api", "ghost"}},
+		Projects: map[string]Project{
+			"api": {RemoteSSHURL: "git@github.com:org/api.git"},
+		},
+	}
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "group 'backend' lists unknown project 'ghost'") {
+		t.Fatalf("expected unknown group member error, got %v", err)
+	}
+}
+
 func TestValidateConfig_Valid(t *testing.T) {
 	cfg := &Config{
 		Home:      "/abs/path",
@@ -233,6 +361,74 @@ func TestUpdateConfig_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestUpdateConfig_KeepsConcurrentChanges(t *testing.T) {
+	configPath := writeConfig(t, validConfig)
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// Another pancake process changes the file after cfg was loaded.
+	if err := ModifyConfig(func(current *Config) error {
+		current.Tools = append(current.Tools, "jq")
+		return nil
+	}); err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+	cfg.Projects["api"] = Project{RemoteSSHURL: "git@github.com:org/api.git"}
+	if err := UpdateConfig(cfg); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"- jq", "remote_ssh_url: git@github.com:org/api.git"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("written config is missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestUpdateConfig_PreservesComments(t *testing.T) {
+	configPath := writeConfig(t, DefaultYMLContent+"custom_section:\n  keep: true # not a pancake setting\n")
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	cfg.Tools = append(cfg.Tools, "jq")
+	delete(cfg.Projects, "june-gpt")
+	cfg.Projects["api"] = Project{RemoteSSHURL: "git@github.com:org/api.git", Build: "go build ./..."}
+	if err := UpdateConfig(cfg); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := string(data)
+	for _, want := range []string{
+		"# Pancake Configuration File.",
+		"home: $HOME/pancake # For MacOS & Linux",
+		"#home: '%userprofile%/pancake' # For Windows",
+		"code_editor: code . # Preferred code editor",
+		"keep: true # not a pancake setting",
+		"- jq",
+		"remote_ssh_url: git@github.com:org/api.git",
+	} {
+		if !strings.Contains(written, want) {
+			t.Errorf("written config is missing %q:\n%s", want, written)
+		}
+	}
+	if strings.Contains(written, "june-gpt") {
+		t.Errorf("removed project is still in the config:\n%s", written)
+	}
+	if strings.Index(written, "home:") > strings.Index(written, "code_editor:") {
+		t.Errorf("key order changed:\n%s", written)
+	}
+}
+
 func TestConfigPath_Absolute(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
@@ -248,3 +444,50 @@ func TestConfigPath_Absolute(t *testing.T) {
 		t.Fatalf("config path should end with %s, got %s", ConfigFileName, got)
 	}
 }
+
+func TestFindConfigPath_Order(t *testing.T) {
+	home := filepath.Dir(writeConfig(t, validConfig))
+	work := filepath.Join(home, "work", "api")
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+	// The temporary directory may be behind a symlink, as on macOS.
+	if work, err = os.Getwd(); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(want, wantSource string) {
+		t.Helper()
+		got, source, err := FindConfigPath()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want || source != wantSource {
+			t.Errorf("got %s (%s), want %s (%s)", got, source, want, wantSource)
+		}
+	}
+	check(filepath.Join(home, ConfigFileName), "default")
+
+	xdgPath := filepath.Join(home, ".config", "pancake", ConfigFileName)
+	writeTestFile(t, xdgPath, validConfig)
+	check(xdgPath, "XDG_CONFIG_HOME")
+
+	localPath := filepath.Join(filepath.Dir(work), LocalConfigFileName)
+	writeTestFile(t, localPath, validConfig)
+	check(localPath, "project-local "+LocalConfigFileName)
+
+	t.Setenv(ConfigPathEnv, filepath.Join(home, "env.yml"))
+	check(filepath.Join(home, "env.yml"), ConfigPathEnv)
+
+	ConfigPathOverride = "flag.yml"
+	t.Cleanup(func() { ConfigPathOverride = "" })
+	check(filepath.Join(work, "flag.yml"), "--config flag")
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// supervisorEnv marks a pancake process that was re-executed by
+// StartDetachedProcess to supervise a project instead of running the CLI.
+const (
+	supervisorEnv      = "PANCAKE_SUPERVISOR"
+	exitedSuccessfully = "exit status 0"
+)
+
+// SupervisorRequested reports whether this process should run RunSupervisor.
+func SupervisorRequested() bool {
+	return os.Getenv(supervisorEnv) == "1" && len(os.Args) == 3
+}
+
+// RunSupervisor starts the command in os.Args[2] in its own process group and
+// copies its output, line by line and timestamped, to the rotating log at
+// os.Args[1] until every process holding the output open has exited. The
+// handshake with StartDetachedProcess happens over stdout.
+func RunSupervisor() int {
+	logPath, cmdStr := os.Args[1], os.Args[2]
+	os.Unsetenv(supervisorEnv)
+
+	logFile, err := OpenRotatingLog(logPath, LogMaxBytes, LogMaxBackups)
+	if err != nil {
+		fmt.Printf("error %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		fmt.Printf("error could not create output pipe: %v\n", err)
+		return 1
+	}
+	child := buildShellCommand(cmdStr)
+	child.Stdout = writer
+	child.Stderr = writer
+	newProcessGroup(child)
+
+	dir, _ := os.Getwd()
+	logFile.WriteLine(fmt.Sprintf("--- pancake: starting '%s' in %s ---", cmdStr, dir))
+	if err := child.Start(); err != nil {
+		logFile.WriteLine(fmt.Sprintf("--- pancake: could not start '%s': %v ---", cmdStr, err))
+		fmt.Printf("error %v\n", err)
+		return 1
+	}
+	writer.Close()
+	fmt.Printf("started %d\n", child.Process.Pid)
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		copyLogLines(reader, logFile)
+	}()
+	exited := make(chan string, 1)
+	go func() {
+		status := exitedSuccessfully
+		if err := child.Wait(); err != nil {
+			status = err.Error()
+		}
+		exited <- status
+	}()
+
+	select {
+	case status := <-exited:
+		<-copied
+		logFile.WriteLine(fmt.Sprintf("--- pancake: '%s' exited (%s) ---", cmdStr, status))
+		fmt.Printf("exited %s\n", status)
+		return 0
+	case <-time.After(startupGraceTime):
+		fmt.Println("running")
+		os.Stdout.Close()
+	}
+	status := <-exited
+	<-copied
+	logFile.WriteLine(fmt.Sprintf("--- pancake: '%s' exited (%s) ---", cmdStr, status))
+	return 0
+}
+
+// copyLogLines writes the lines read from r to log until r is closed. Lines
+// longer than the read buffer are split rather than dropped, and if reading
+// fails r is still drained, so the project never blocks writing its output.
+func copyLogLines(r io.Reader, log *RotatingLog) {
+	lines := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, _, err := lines.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				log.WriteLine(fmt.Sprintf("--- pancake: could not read the output: %v ---", err))
+				io.Copy(io.Discard, r)
+			}
+			return
+		}
+		log.WriteLine(string(line))
+	}
+}
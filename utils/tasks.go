@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// taskReferencePrefix marks a step that runs another task of the same
+// project instead of a shell command, e.g. "@lint". "@build" and "@run" refer
+// to the project's build and run commands.
+const taskReferencePrefix = "@"
+
+// TaskReference returns the task a step refers to, if it is a reference.
+func TaskReference(step string) (string, bool) {
+	step = strings.TrimSpace(step)
+	if !strings.HasPrefix(step, taskReferencePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(step, taskReferencePrefix), true
+}
+
+// TaskNames lists the tasks 'pancake do' can run for a project, sorted.
+func TaskNames(project Project) []string {
+	names := make([]string, 0, len(project.Commands)+2)
+	for name := range project.Commands {
+		names = append(names, name)
+	}
+	for name, command := range map[string]string{"build": project.Build, "run": project.Run} {
+		if _, defined := project.Commands[name]; !defined && command != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func taskSteps(project Project, task string) (StringList, bool) {
+	if steps, ok := project.Commands[task]; ok {
+		return steps, true
+	}
+	switch {
+	case task == "build" && project.Build != "":
+		return StringList{project.Build}, true
+	case task == "run" && project.Run != "":
+		return StringList{project.Run}, true
+	}
+	return nil, false
+}
+
+// ExpandTask flattens a task into the shell commands it runs, in order,
+// following references to other tasks.
+func ExpandTask(project Project, task string) ([]string, error) {
+	var commands []string
+	var expand func(task string, path []string) error
+	expand = func(task string, path []string) error {
+		for _, seen := range path {
+			if seen == task {
+				return fmt.Errorf("task %s calls itself: %s", task, strings.Join(append(path, task), " -> "))
+			}
+		}
+		steps, ok := taskSteps(project, task)
+		if !ok {
+			if len(path) == 0 {
+				return fmt.Errorf("unknown task '%s'", task)
+			}
+			return fmt.Errorf("task %s calls unknown task '%s'", path[len(path)-1], task)
+		}
+		for _, step := range steps {
+			if reference, ok := TaskReference(step); ok {
+				if err := expand(reference, append(path, task)); err != nil {
+					return err
+				}
+				continue
+			}
+			commands = append(commands, step)
+		}
+		return nil
+	}
+	if err := expand(task, nil); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandTask(t *testing.T) {
+	project := Project{
+		Build: "go build ./...",
+		Commands: map[string]StringList{
+			"lint": {"go vet ./..."},
+			"unit": {"go test ./..."},
+			"ci":   {"@lint", "@build", "@unit", "echo done"},
+		},
+	}
+	commands, err := ExpandTask(project, "ci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"go vet ./...", "go build ./...", "go test ./...", "echo done"}
+	if !reflect.DeepEqual(commands, want) {
+		t.Fatalf("commands = %v, want %v", commands, want)
+	}
+}
+
+func TestExpandTask_Errors(t *testing.T) {
+	project := Project{
+		Commands: map[string]StringList{
+			"a":      {"@b"},
+			"b":      {"@a"},
+			"broken": {"@missing"},
+		},
+	}
+	if _, err := ExpandTask(project, "a"); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+	if _, err := ExpandTask(project, "broken"); err == nil || !strings.Contains(err.Error(), "unknown task 'missing'") {
+		t.Fatalf("expected an unknown task error, got %v", err)
+	}
+	if _, err := ExpandTask(project, "nope"); err == nil {
+		t.Fatal("expected an error for an unknown task")
+	}
+}
+
+func TestTaskNames(t *testing.T) {
+	project := Project{Run: "npm start", Commands: map[string]StringList{"test": {"npm test"}}}
+	if names := TaskNames(project); !reflect.DeepEqual(names, []string{"run", "test"}) {
+		t.Fatalf("TaskNames = %v", names)
+	}
+}
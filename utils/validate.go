@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ConfigDiagnostic is a ConfigIssue together with where it is. File is the
+// config file or the include entry the setting comes from. Line and Column
+// are 1-based, or 0 if unknown.
+type ConfigDiagnostic struct {
+	File   string
+	Line   int
+	Column int
+	ConfigIssue
+}
+
+// Position returns "file:line:column", leaving out what is unknown.
+func (d ConfigDiagnostic) Position() string {
+	position := d.File
+	if d.Line > 0 {
+		position += ":" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			position += ":" + strconv.Itoa(d.Column)
+		}
+	}
+	return position
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// ValidateConfigFile checks the config file at configPath, merged with the
+// files it includes, and returns every problem found, ordered by position.
+// Unlike GetConfig it reports keys pancake does not know and projects that
+// share a port, and it never changes the file. The error is set only if
+// configPath cannot be read.
+func ValidateConfigFile(configPath string) ([]ConfigDiagnostic, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(fmt.Sprintf(ConfigErrNotFound, configPath))
+		}
+		return nil, errors.New(fmt.Sprintf(ConfigErrReadFailed, configPath, configPath))
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		diagnostic := ConfigDiagnostic{File: configPath, ConfigIssue: ConfigIssue{
+			Message: fmt.Sprintf(ConfigErrParseFailed, err.Error(), configPath),
+		}}
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			diagnostic.Line, _ = strconv.Atoi(match[1])
+		}
+		return []ConfigDiagnostic{diagnostic}, nil
+	}
+	local := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	if len(doc.Content) > 0 {
+		local = doc.Content[0]
+	}
+	at := func(file string, node *yamlv3.Node, issue ConfigIssue) ConfigDiagnostic {
+		if file == "" {
+			file = configPath
+		}
+		return ConfigDiagnostic{File: file, Line: node.Line, Column: node.Column, ConfigIssue: issue}
+	}
+
+	var diagnostics []ConfigDiagnostic
+	version, err := ConfigFileVersion(data)
+	versionKey := lookupConfigNode(local, []string{"version"})
+	switch {
+	case err != nil:
+		diagnostics = append(diagnostics, at(configPath, versionKey, ConfigIssue{Path: []string{"version"}, Message: err.Error()}))
+	case version > CurrentConfigVersion:
+		diagnostics = append(diagnostics, at(configPath, versionKey, ConfigIssue{
+			Path:    []string{"version"},
+			Message: fmt.Sprintf(ConfigErrVersionTooNew, version, CurrentConfigVersion),
+		}))
+	case version < CurrentConfigVersion:
+		diagnostics = append(diagnostics, at(configPath, versionKey, ConfigIssue{
+			Path:    []string{"version"},
+			Message: fmt.Sprintf(ConfigWarnVersionOld, version, CurrentConfigVersion),
+			Warning: true,
+		}))
+	}
+
+	resolved, err := ResolveConfigIncludes(configPath, data, false)
+	if err != nil {
+		diagnostics = append(diagnostics, at(configPath, lookupConfigNode(local, []string{"include"}), ConfigIssue{
+			Path:    []string{"include"},
+			Message: fmt.Sprintf(ConfigErrIncludeFailed, err.Error()),
+		}))
+		return sortDiagnostics(diagnostics), nil
+	}
+
+	var found []configNodeIssue
+	checkConfigNode(resolved.Root, reflect.TypeOf(Config{}), nil, &found)
+	typesValid := true
+	for _, issue := range found {
+		diagnostics = append(diagnostics, at(resolved.File(issue.node), issue.node, issue.ConfigIssue))
+		typesValid = typesValid && issue.Warning
+	}
+	if !typesValid {
+		// The settings cannot be decoded, so their values are not checked.
+		return sortDiagnostics(diagnostics), nil
+	}
+
+	config, err := decodeConfig(configPath, data)
+	if err != nil {
+		diagnostics = append(diagnostics, ConfigDiagnostic{File: configPath, ConfigIssue: ConfigIssue{Message: err.Error()}})
+		return sortDiagnostics(diagnostics), nil
+	}
+	for _, issue := range configIssues(config) {
+		node := lookupConfigNode(resolved.Root, config.settingPath(issue.Path))
+		diagnostics = append(diagnostics, at(resolved.File(node), node, issue))
+	}
+	return sortDiagnostics(diagnostics), nil
+}
+
+// lookupConfigNode returns the key of the setting at path, the list item
+// path ends in, or the closest enclosing one if the setting is not written
+// out in the file.
+func lookupConfigNode(root *yamlv3.Node, path []string) *yamlv3.Node {
+	node, found := root, root
+	for _, key := range path {
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			index := yamlMappingIndex(node, key)
+			if index < 0 {
+				return found
+			}
+			found, node = node.Content[index], node.Content[index+1]
+		case yamlv3.SequenceNode:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return found
+			}
+			found, node = node.Content[index], node.Content[index]
+		default:
+			return found
+		}
+	}
+	return found
+}
+
+func sortDiagnostics(diagnostics []ConfigDiagnostic) []ConfigDiagnostic {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diagnostics
+}
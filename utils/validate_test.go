@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func findDiagnostic(diagnostics []ConfigDiagnostic, text string) *ConfigDiagnostic {
+	for i := range diagnostics {
+		if strings.Contains(diagnostics[i].Message, text) {
+			return &diagnostics[i]
+		}
+	}
+	return nil
+}
+
+func TestValidateConfigFile_Positions(t *testing.T) {
+	configPath := writeConfig(t, `version: 1
+home: /tmp/pancake
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    biuld: make
+    port: "3000"
+  web:
+    remote_ssh_url: git@github.com:org/web.git
+    port: 3000
+    depends_on: [api, missing]
+`)
+	diagnostics, err := ValidateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		text         string
+		line, column int
+		warning      bool
+	}{
+		{"'projects.api.biuld' (did you mean 'build'?)", 6, 5, true},
+		{"same 'port' as project 'api'", 10, 5, true},
+		{"unknown project 'missing'", 11, 23, false},
+	}
+	for _, c := range cases {
+		diagnostic := findDiagnostic(diagnostics, c.text)
+		if diagnostic == nil {
+			t.Errorf("no diagnostic containing %q in %+v", c.text, diagnostics)
+			continue
+		}
+		if diagnostic.File != configPath || diagnostic.Line != c.line || diagnostic.Column != c.column || diagnostic.Warning != c.warning {
+			t.Errorf("%q: got %s (warning %v)", c.text, diagnostic.Position(), diagnostic.Warning)
+		}
+	}
+	if len(diagnostics) != len(cases) {
+		t.Errorf("expected %d diagnostics, got %+v", len(cases), diagnostics)
+	}
+}
+
+func TestValidateConfigFile_TypeErrors(t *testing.T) {
+	configPath := writeConfig(t, `version: 1
+home: /tmp/pancake
+tools: git
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    depth: lots
+    submodules: yes please
+`)
+	diagnostics, err := ValidateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"'tools' must be a list, not 'git'",
+		"'projects.api.depth' must be a whole number, not 'lots'",
+		"'projects.api.submodules' must be true or false",
+	} {
+		if diagnostic := findDiagnostic(diagnostics, want); diagnostic == nil || diagnostic.Warning {
+			t.Errorf("expected an error containing %q, got %+v", want, diagnostics)
+		}
+	}
+}
+
+func TestValidateConfigFile_YAML11Booleans(t *testing.T) {
+	const project = `version: 1
+home: /tmp/pancake
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    submodules: %s
+`
+	configPath := writeConfig(t, fmt.Sprintf(project, "yes"))
+	config, err := GetConfig()
+	if err != nil || !config.Projects["api"].Submodules {
+		t.Fatalf("expected 'submodules: yes' to load as true: %v", err)
+	}
+	diagnostics, err := ValidateConfigFile(configPath)
+	if err != nil || len(diagnostics) != 0 {
+		t.Errorf("expected 'submodules: yes' to be valid, got %+v (%v)", diagnostics, err)
+	}
+
+	configPath = writeConfig(t, fmt.Sprintf(project, `"yes"`))
+	diagnostics, err = ValidateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if findDiagnostic(diagnostics, "'projects.api.submodules' must be true or false") == nil {
+		t.Errorf("expected a quoted \"yes\" to be reported, got %+v", diagnostics)
+	}
+}
+
+func TestValidateConfigFile_IncludeAndSyntax(t *testing.T) {
+	configPath := writeConfig(t, `version: 1
+include: team.yml
+home: /tmp/pancake
+`)
+	writeTestFile(t, filepath.Join(filepath.Dir(configPath), "team.yml"), `projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    prot: 80
+`)
+	diagnostics, err := ValidateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Position() != "team.yml:4:5" {
+		t.Errorf("expected one diagnostic at team.yml:4:5, got %+v", diagnostics)
+	}
+
+	configPath = writeConfig(t, "home: /tmp\nprojects: [\n")
+	diagnostics, err = ValidateConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Warning || diagnostics[0].Line == 0 {
+		t.Errorf("expected a syntax error with a line, got %+v", diagnostics)
+	}
+}
+
+func TestValidateConfigFile_Valid(t *testing.T) {
+	configPath := writeConfig(t, "version: 1\n"+validConfig)
+	diagnostics, err := ValidateConfigFile(configPath)
+	if err != nil || len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %+v (%v)", diagnostics, err)
+	}
+}
+
+func TestConfigJSONSchema(t *testing.T) {
+	data, err := ConfigJSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	object := func(value interface{}, key string) map[string]interface{} {
+		m, _ := value.(map[string]interface{})
+		child, _ := m[key].(map[string]interface{})
+		return child
+	}
+	projects := object(object(schema, "properties"), "projects")
+	project := object(object(projects, "additionalProperties"), "properties")
+	for _, field := range []string{"remote_ssh_url", "build", "run", "port", "health", "commands"} {
+		if _, ok := project[field]; !ok {
+			t.Errorf("project schema has no %q", field)
+		}
+	}
+	if object(project, "build")["description"] == nil {
+		t.Error("expected project fields to be described")
+	}
+}
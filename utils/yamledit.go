@@ -0,0 +1,324 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// EditYAML applies the difference between before and after to the YAML
+// document original and returns the new document. before is the value
+// original decodes to and after is the value to write. Only keys whose
+// values differ are touched, so comments, key order, quoting, blank lines
+// and keys that do not map to a field of after are kept as they are.
+func EditYAML(original []byte, before, after interface{}) ([]byte, error) {
+	var beforeNode, afterNode yamlv3.Node
+	if err := beforeNode.Encode(before); err != nil {
+		return nil, err
+	}
+	if err := afterNode.Encode(after); err != nil {
+		return nil, err
+	}
+
+	if yamlNodesEqual(&beforeNode, &afterNode) && len(original) > 0 {
+		return original, nil
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(original, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) == 0 {
+		doc = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{&afterNode}}
+		return encodeYAML(&doc)
+	}
+	canonical, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, err
+	}
+	doc.Content[0] = mergeYAMLNode(doc.Content[0], &beforeNode, &afterNode)
+	edited, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return keepLayout(original, canonical, edited), nil
+}
+
+func encodeYAML(doc *yamlv3.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("could not encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// keepLayout returns edited with the text of original wherever the edit did
+// not change the document. canonical and edited are yaml.v3's encodings of
+// original before and after the edit, which drop blank lines and may space
+// or indent things differently. original is aligned with canonical line by
+// line: the parts of original whose canonical lines are all still in
+// edited are copied as they are, and only the lines around a change are
+// taken from edited.
+func keepLayout(original, canonical, edited []byte) []byte {
+	lines := splitLines(string(original))
+	before := splitLines(string(canonical))
+
+	// kept[k] tells whether line k of canonical is still in edited, and
+	// added[k] holds the lines edited has between lines k-1 and k of it.
+	kept := make([]bool, len(before))
+	added := make([][]string, len(before)+1)
+	for _, change := range lineEdits(before, splitLines(string(edited))) {
+		switch change.op {
+		case ' ':
+			kept[change.lineA] = true
+		case '+':
+			added[change.lineA] = append(added[change.lineA], change.line)
+		}
+	}
+	isKept := func(k int) bool { return k >= 0 && k < len(kept) && kept[k] }
+
+	out := append([]string(nil), added[0]...)
+	alignment := lineEdits(lines, before)
+	for start := 0; start < len(alignment); {
+		if step := alignment[start]; step.op == ' ' {
+			if kept[step.lineB] {
+				out = append(out, lines[step.lineA])
+			}
+			out = append(out, added[step.lineB+1]...)
+			start++
+			continue
+		}
+
+		// A run of lines that original and canonical write differently.
+		end := start
+		var own []string
+		var same []int
+		for ; end < len(alignment) && alignment[end].op != ' '; end++ {
+			if alignment[end].op == '-' {
+				own = append(own, lines[alignment[end].lineA])
+			} else {
+				same = append(same, alignment[end].lineB)
+			}
+		}
+		next := alignment[start].lineB
+		start = end
+
+		if len(same) == 0 {
+			// Text yaml.v3 leaves out, such as blank lines. A blank line
+			// goes when the lines on both sides of it were removed.
+			for _, line := range own {
+				if strings.TrimSpace(line) != "" || isKept(next-1) || isKept(next) {
+					out = append(out, line)
+				}
+			}
+			continue
+		}
+		unchanged := true
+		for i, k := range same {
+			unchanged = unchanged && kept[k] && (i == len(same)-1 || len(added[k+1]) == 0)
+		}
+		if unchanged {
+			out = append(out, own...)
+			out = append(out, added[same[len(same)-1]+1]...)
+			continue
+		}
+		if paired, ok := pairLines(own, same, before); ok {
+			// Each line of original is canonical's with more or less
+			// indentation: keep those that did not change and indent the
+			// new ones like them.
+			for _, line := range own {
+				if strings.TrimSpace(line) == "" {
+					out = append(out, line)
+					continue
+				}
+				k := paired[0]
+				paired = paired[1:]
+				if kept[k] {
+					out = append(out, line)
+				}
+				out = append(out, reindent(added[k+1], indentation(line)-indentation(before[k]))...)
+			}
+			continue
+		}
+		leading := 0
+		for leading < len(own) && strings.TrimSpace(own[leading]) == "" {
+			leading++
+		}
+		trailing := len(own)
+		for trailing > leading && strings.TrimSpace(own[trailing-1]) == "" {
+			trailing--
+		}
+		out = append(out, own[:leading]...)
+		for _, k := range same {
+			if kept[k] {
+				out = append(out, before[k])
+			}
+			out = append(out, added[k+1]...)
+		}
+		out = append(out, own[trailing:]...)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// pairLines matches the non-blank lines of own one to one with the lines
+// same of canonical. It fails unless there are as many of each and every
+// pair differs in indentation by the same amount.
+func pairLines(own []string, same []int, canonical []string) ([]int, bool) {
+	var paired []int
+	shift, i := 0, 0
+	for _, line := range own {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if i == len(same) {
+			return nil, false
+		}
+		k := same[i]
+		difference := indentation(line) - indentation(canonical[k])
+		if i > 0 && difference != shift {
+			return nil, false
+		}
+		shift = difference
+		paired = append(paired, k)
+		i++
+	}
+	return paired, i == len(same)
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func reindent(lines []string, shift int) []string {
+	if shift == 0 {
+		return lines
+	}
+	reindented := make([]string, len(lines))
+	for i, line := range lines {
+		if shift > 0 {
+			reindented[i] = strings.Repeat(" ", shift) + line
+		} else {
+			reindented[i] = line[min(-shift, indentation(line)):]
+		}
+	}
+	return reindented
+}
+
+// mergeYAMLNode returns current updated to after. before is current as
+// pancake understood it, or nil if current has no counterpart in before.
+func mergeYAMLNode(current, before, after *yamlv3.Node) *yamlv3.Node {
+	if before != nil && yamlNodesEqual(before, after) {
+		return current
+	}
+	switch {
+	case current.Kind == yamlv3.MappingNode && after.Kind == yamlv3.MappingNode:
+		return mergeYAMLMapping(current, before, after)
+	case current.Kind == yamlv3.SequenceNode && after.Kind == yamlv3.SequenceNode:
+		return mergeYAMLSequence(current, before, after)
+	}
+	after.HeadComment = current.HeadComment
+	after.LineComment = current.LineComment
+	after.FootComment = current.FootComment
+	return after
+}
+
+func mergeYAMLMapping(current, before, after *yamlv3.Node) *yamlv3.Node {
+	if len(current.Content) == 0 {
+		current.Style = 0 // expand an empty '{}' when adding to it
+	}
+	for i := 0; i+1 < len(after.Content); i += 2 {
+		key, value := after.Content[i], after.Content[i+1]
+		beforeValue := yamlMappingValue(before, key.Value)
+		if index := yamlMappingIndex(current, key.Value); index >= 0 {
+			current.Content[index+1] = mergeYAMLNode(current.Content[index+1], beforeValue, value)
+		} else if beforeValue != nil && beforeValue.Kind == yamlv3.MappingNode && value.Kind == yamlv3.MappingNode {
+			// The mapping only exists in before, e.g. through an include:
+			// write just the keys that changed.
+			if !yamlNodesEqual(beforeValue, value) {
+				added := mergeYAMLMapping(&yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}, beforeValue, value)
+				current.Content = append(current.Content, key, added)
+			}
+		} else if beforeValue == nil || !yamlNodesEqual(beforeValue, value) {
+			current.Content = append(current.Content, key, value)
+		}
+	}
+	// Drop keys that pancake knew about but that are now unset. Keys it
+	// never decoded are unknown to it and are left alone.
+	for i := 0; i+1 < len(current.Content); {
+		key := current.Content[i].Value
+		if yamlMappingIndex(after, key) < 0 && yamlMappingIndex(before, key) >= 0 {
+			current.Content = append(current.Content[:i], current.Content[i+2:]...)
+			continue
+		}
+		i += 2
+	}
+	return current
+}
+
+// mergeYAMLSequence rebuilds a sequence from after, reusing the existing
+// items (and their comments) that are still present.
+func mergeYAMLSequence(current, before, after *yamlv3.Node) *yamlv3.Node {
+	if len(current.Content) == 0 {
+		current.Style = 0
+	}
+	aligned := before != nil && before.Kind == yamlv3.SequenceNode && len(before.Content) == len(current.Content)
+	used := make([]bool, len(current.Content))
+	content := make([]*yamlv3.Node, 0, len(after.Content))
+	for _, item := range after.Content {
+		reused := false
+		for i := range current.Content {
+			if aligned && !used[i] && yamlNodesEqual(before.Content[i], item) {
+				content = append(content, current.Content[i])
+				used[i] = true
+				reused = true
+				break
+			}
+		}
+		if !reused {
+			content = append(content, item)
+		}
+	}
+	current.Content = content
+	return current
+}
+
+func yamlMappingIndex(node *yamlv3.Node, key string) int {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func yamlMappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if index := yamlMappingIndex(node, key); index >= 0 {
+		return node.Content[index+1]
+	}
+	return nil
+}
+
+func yamlNodesEqual(a, b *yamlv3.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !yamlNodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func editTestConfig(t *testing.T, original string, edit func(*Config)) string {
+	t.Helper()
+	var before, after Config
+	if err := yaml.Unmarshal([]byte(original), &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(original), &after); err != nil {
+		t.Fatal(err)
+	}
+	edit(&after)
+	data, err := EditYAML([]byte(original), &before, &after)
+	if err != nil {
+		t.Fatalf("EditYAML: %v", err)
+	}
+	return string(data)
+}
+
+func TestEditYAML_Unchanged(t *testing.T) {
+	original := `home: $HOME/pancake
+tools: [tree] # flow style
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+    port: "3000"
+    env_file: .env
+`
+	if got := editTestConfig(t, original, func(*Config) {}); got != original {
+		t.Errorf("unchanged config was rewritten:\n%s", got)
+	}
+}
+
+func TestEditYAML_Sequence(t *testing.T) {
+	original := `tools:
+  - tree # file listing
+  - jq
+`
+	got := editTestConfig(t, original, func(c *Config) {
+		c.Tools = []string{"tree", "git"}
+	})
+	want := `tools:
+  - tree # file listing
+  - git
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEditYAML_DoesNotAddDefaults(t *testing.T) {
+	original := "home: /srv/pancake\n"
+	got := editTestConfig(t, original, func(c *Config) {
+		c.CodeEditor = "idea ."
+	})
+	if got != "home: /srv/pancake\ncode_editor: idea .\n" {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+	if strings.Contains(got, "gemini") {
+		t.Errorf("zero-valued sections were added:\n%s", got)
+	}
+}
+
+func TestEditYAML_ScalarToList(t *testing.T) {
+	original := "env_file: .env\n"
+	got := editTestConfig(t, original, func(c *Config) {
+		c.EnvFile = append(c.EnvFile, ".env.local")
+	})
+	if got != "env_file:\n  - .env\n  - .env.local\n" {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+}
+
+func TestEditYAML_KeepsLayoutOutsideChanges(t *testing.T) {
+	head := `# Pancake Configuration File.
+home: $HOME/pancake   # two spaces before this comment
+
+code_editor: code .
+
+gemini:
+    api_key: ""     # indented by four
+    temperature: 0.7
+
+tools:
+  - tree
+projects:
+  api:
+    remote_ssh_url: git@github.com:org/api.git
+`
+	tail := `
+# Team groups
+groups:
+  backend: [api]
+`
+	original := head + tail
+	got := editTestConfig(t, original, func(c *Config) {
+		c.Projects["web"] = Project{RemoteSSHURL: "git@github.com:org/web.git"}
+	})
+	want := head + `  web:
+    remote_ssh_url: git@github.com:org/web.git
+` + tail
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = editTestConfig(t, original, func(c *Config) {
+		c.Tools = append(c.Tools, "jq")
+		c.Gemini.Temperature = 0.2
+	})
+	want = strings.Replace(strings.Replace(original, "  - tree\n", "  - tree\n  - jq\n", 1), "temperature: 0.7", "temperature: 0.2", 1)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = editTestConfig(t, original, func(c *Config) {
+		delete(c.Projects, "api")
+		c.Groups = nil
+	})
+	want = strings.Replace(head, "  api:\n    remote_ssh_url: git@github.com:org/api.git\n", "", 1)
+	want = strings.Replace(want, "projects:\n", "projects: {}\n", 1)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEditYAML_DefaultConfigOnlyGainsLines(t *testing.T) {
+	got := editTestConfig(t, DefaultYMLContent, func(c *Config) {
+		c.Projects["api"] = Project{RemoteSSHURL: "git@github.com:org/api.git", Port: "8080"}
+		c.Tools = append(c.Tools, "jq")
+	})
+	added := 0
+	for _, change := range lineEdits(splitLines(DefaultYMLContent), splitLines(got)) {
+		switch change.op {
+		case '-':
+			t.Errorf("line %d of the default config was changed: %q", change.lineA+1, change.line)
+		case '+':
+			added++
+		}
+	}
+	if added != 4 {
+		t.Errorf("expected 4 new lines, got:\n%s", UnifiedDiff(DefaultYMLContent, got, "before", "after"))
+	}
+}
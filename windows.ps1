@@ -355,6 +355,7 @@ If pancake is still running, kill it via Task Manager and retry."
             Remove-Item $configFile -Force
             Write-Log "Removed $configFile"
         }
+        Remove-Item "$configFile.lock" -Force -ErrorAction SilentlyContinue
         if (Test-Path $pancakeHome) {
             if (Confirm-Action "Also remove pancake project directory $pancakeHome? This deletes all synced projects.") {
                 Remove-Item $pancakeHome -Recurse -Force